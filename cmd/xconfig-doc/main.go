@@ -0,0 +1,127 @@
+// Command xconfig-doc generates the documentation and templates of a config struct.
+//
+// Usage, in the module of the config package:
+//
+//	go run github.com/crestalnetwork/crestal-go-utils/cmd/xconfig-doc -pkg ./config -type Config -format markdown
+//
+// The formats are markdown, dotenv (a .env.example) and k8s (a container env and Secret skeleton).
+// It builds and runs a temporary program in the module, so the config package must compile.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+var program = template.Must(template.New("main").Parse(`package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/crestalnetwork/crestal-go-utils/xconfig"
+	config {{ printf "%q" .ImportPath }}
+)
+
+func main() {
+	dst := new(config.{{ .Type }})
+	var err error
+	switch {{ printf "%q" .Format }} {
+	case "markdown":
+		err = xconfig.WriteMarkdown(os.Stdout, dst)
+	case "dotenv":
+		err = xconfig.WriteDotenvExample(os.Stdout, dst)
+	case "k8s":
+		err = xconfig.WriteK8sManifest(os.Stdout, dst, {{ printf "%q" .SecretName }})
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`))
+
+func main() {
+	pkg := flag.String("pkg", ".", "directory of the config package")
+	typ := flag.String("type", "Config", "name of the config struct type")
+	format := flag.String("format", "markdown", "output format: markdown, dotenv or k8s")
+	secretName := flag.String("secret", "app-secrets", "name of the kubernetes Secret, for the k8s format")
+	output := flag.String("o", "", "output file, default stdout")
+	flag.Parse()
+
+	switch *format {
+	case "markdown", "dotenv", "k8s":
+	default:
+		fatal(fmt.Errorf("unknown format %q", *format))
+	}
+
+	importPath, err := goList(*pkg, "list", "-f", "{{.ImportPath}}", ".")
+	if err != nil {
+		fatal(err)
+	}
+	moduleDir, err := goList(*pkg, "list", "-m", "-f", "{{.Dir}}")
+	if err != nil {
+		fatal(err)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			fatal(err)
+		}
+		defer out.Close()
+	}
+
+	// the program must be inside the module to import the config package
+	dir, err := os.MkdirTemp(moduleDir, "xconfig-doc-")
+	if err != nil {
+		fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var src bytes.Buffer
+	err = program.Execute(&src, map[string]string{
+		"ImportPath": importPath,
+		"Type":       *typ,
+		"Format":     *format,
+		"SecretName": *secretName,
+	})
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, "main.go"), src.Bytes(), 0o644)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", "./"+filepath.Base(dir))
+	cmd.Dir = moduleDir
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(dir)
+		fatal(fmt.Errorf("run generator failed: %w", err))
+	}
+}
+
+// goList runs the go command in dir and returns the trimmed output
+func goList(dir string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go %s failed: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "xconfig-doc:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,94 @@
+// Command xconfig-encrypt encrypts config values to commit them as enc:v1:... in dotenv, yaml files or env.
+//
+// Usage:
+//
+//	go run github.com/crestalnetwork/crestal-go-utils/cmd/xconfig-encrypt -genkey > config_encryption_key
+//	echo -n secret | go run github.com/crestalnetwork/crestal-go-utils/cmd/xconfig-encrypt -key-file config_encryption_key
+//	go run github.com/crestalnetwork/crestal-go-utils/cmd/xconfig-encrypt -d enc:v1:...
+//
+// The key is the base64 AES key of -key-file, env CONFIG_ENCRYPTION_KEY or the file of CONFIG_ENCRYPTION_KEY_FILE.
+// Values are the arguments, or the lines of stdin so they don't end up in the shell history.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/crestalnetwork/crestal-go-utils/xconfig"
+)
+
+func main() {
+	genKey := flag.Bool("genkey", false, "print a new random 256-bit key")
+	keyFile := flag.String("key-file", "", "file of the base64 key, default env CONFIG_ENCRYPTION_KEY or CONFIG_ENCRYPTION_KEY_FILE")
+	decrypt := flag.Bool("d", false, "decrypt the values instead")
+	flag.Parse()
+
+	if *genKey {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			fatal(err)
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+		return
+	}
+
+	c, err := loadCipher(*keyFile)
+	if err != nil {
+		fatal(err)
+	}
+	convert := xconfig.EncryptValue
+	if *decrypt {
+		convert = xconfig.DecryptValue
+	}
+	ctx := context.Background()
+	run := func(value string) {
+		out, err := convert(ctx, c, value)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(out)
+	}
+
+	if flag.NArg() > 0 {
+		for _, value := range flag.Args() {
+			run(value)
+		}
+		return
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		run(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		fatal(err)
+	}
+}
+
+// loadCipher reads the key like the loader does
+func loadCipher(keyFile string) (xconfig.Cipher, error) {
+	if keyFile == "" {
+		if key := os.Getenv("CONFIG_ENCRYPTION_KEY"); key != "" {
+			return xconfig.ParseEncryptionKey(key)
+		}
+		keyFile = os.Getenv("CONFIG_ENCRYPTION_KEY_FILE")
+	}
+	if keyFile == "" {
+		return nil, errors.New("no key, use -key-file or set CONFIG_ENCRYPTION_KEY")
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return xconfig.ParseEncryptionKey(string(data))
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "xconfig-encrypt:", err)
+	os.Exit(1)
+}
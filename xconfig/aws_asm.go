@@ -0,0 +1,133 @@
+package xconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// AwsSecretsManagerIds the environment variable name for AWS Secrets Manager secret ids, separated by comma
+const AwsSecretsManagerIds = "AWS_SECRETS_MANAGER_IDS"
+
+// AwsSecretsManagerClient is the part of secretsmanager.Client used by the source, it can be replaced by a fake in tests.
+type AwsSecretsManagerClient interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+var awsAsmClient AwsSecretsManagerClient
+
+// loadAwsAsmClient returns the client set by SetAwsSecretsManagerClient, or a client of the aws config.
+func loadAwsAsmClient(ctx context.Context) (AwsSecretsManagerClient, error) {
+	if awsAsmClient != nil {
+		return awsAsmClient, nil
+	}
+	cfg, err := loadAwsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return secretsmanager.NewFromConfig(cfg), nil
+}
+
+type awsAsmSource struct {
+	client    AwsSecretsManagerClient
+	secretIds []string
+	values    map[string]string
+}
+
+// AwsSecretsManagerSource looks up values in AWS Secrets Manager secrets, if client is nil, it uses the client of
+// SetAwsSecretsManagerClient or the aws config.
+// A JSON object secret is expanded into upper snake keys, nested objects are joined by "_",
+// e.g. {"db": {"password": "x"}} gives DB_PASSWORD. A plain string secret is keyed by its secret id.
+// The key is the `asm` tag or upper snake path like DB_PASSWORD, a later secret overrides the earlier ones.
+func AwsSecretsManagerSource(client AwsSecretsManagerClient, secretIds ...string) Source {
+	return &awsAsmSource{client: client, secretIds: secretIds}
+}
+
+func (s *awsAsmSource) Name() string {
+	return "aws_asm"
+}
+
+func (s *awsAsmSource) Lookup(field Field) (string, string, bool) {
+	key := field.Key("asm", true)
+	if value, ok := s.values[key]; ok {
+		return value, key, true
+	}
+	// json keys are stored upper case
+	value, ok := s.values[strings.ToUpper(key)]
+	return value, key, ok
+}
+
+func (s *awsAsmSource) Scan(field Field) []string {
+	return scanKeys(mapKeys(s.values), strings.ToUpper(field.Key("asm", true)))
+}
+
+// Preload AWS Secrets Manager secrets to memory
+func (s *awsAsmSource) Preload(ctx context.Context) error {
+	if s.client == nil {
+		client, err := loadAwsAsmClient(ctx)
+		if err != nil {
+			return err
+		}
+		s.client = client
+	}
+
+	values := make(map[string]string)
+	for _, id := range s.secretIds {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		res, err := s.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(id),
+		})
+		if err != nil {
+			return fmt.Errorf("get secret %s from aws failed: %w", id, err)
+		}
+		var secret string
+		if res.SecretString != nil {
+			secret = *res.SecretString
+		} else {
+			secret = string(res.SecretBinary)
+		}
+		// json object will be expanded, otherwise it is a plain string
+		var obj map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(secret))
+		decoder.UseNumber() // keep big numbers
+		if err := decoder.Decode(&obj); err == nil && !decoder.More() {
+			flattenJSON(values, "", obj)
+		} else {
+			values[id] = secret
+		}
+	}
+
+	s.values = values
+	return nil
+}
+
+// flattenJSON writes the leaves of obj into dst with upper snake keys
+func flattenJSON(dst map[string]string, prefix string, obj map[string]interface{}) {
+	for k, v := range obj {
+		key := strings.ToUpper(k)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			flattenJSON(dst, key, val)
+		case string:
+			dst[key] = val
+		case json.Number:
+			dst[key] = val.String()
+		case nil:
+			// skip null
+		default:
+			// bools and arrays keep the json form, it can be parsed by yaml
+			data, _ := json.Marshal(val)
+			dst[key] = string(data)
+		}
+	}
+}
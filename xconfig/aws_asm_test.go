@@ -0,0 +1,52 @@
+package xconfig
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSecretsManager map[string]string
+
+func (f fakeSecretsManager) GetSecretValue(_ context.Context, params *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	v, ok := f[*params.SecretId]
+	if !ok {
+		return nil, errors.New("secret not found")
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(v)}, nil
+}
+
+func TestAwsSecretsManagerSource(t *testing.T) {
+	type asmConfig struct {
+		AppName string `default:"default_app"`
+		Token   string `asm:"prod/token"`
+		DB      struct {
+			Host     string
+			Port     int
+			Password string
+			Name     string `asm:"dbname"`
+		}
+	}
+	client := fakeSecretsManager{
+		"prod/db":       `{"db": {"host": "db.local", "port": 5432, "password": "old"}, "dbname": "app"}`,
+		"prod/db-extra": `{"DB_PASSWORD": "new"}`,
+		"prod/token":    "plain-token",
+	}
+
+	cfg := new(asmConfig)
+	err := New(AwsSecretsManagerSource(client, "prod/db", "prod/db-extra", "prod/token")).Load(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "default_app", cfg.AppName)
+	assert.Equal(t, "plain-token", cfg.Token)
+	assert.Equal(t, "db.local", cfg.DB.Host)
+	assert.Equal(t, 5432, cfg.DB.Port)
+	assert.Equal(t, "new", cfg.DB.Password)
+	assert.Equal(t, "app", cfg.DB.Name)
+
+	err = New(AwsSecretsManagerSource(client, "missing")).Load(new(asmConfig))
+	assert.Error(t, err)
+}
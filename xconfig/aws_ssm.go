@@ -3,7 +3,9 @@ package xconfig
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -13,51 +15,156 @@ import (
 // AwsSsmParamStorePath the environment variable name for AWS SSM Param Store
 const AwsSsmParamStorePath = "AWS_SSM_PARAM_STORE_PATH"
 
-var awsConfig *aws.Config
+var (
+	awsConfig    *aws.Config
+	awsSsmClient AwsSsmClient
+)
+
+// AwsSsmClient is the part of ssm.Client used by the loader, it can be replaced by a fake in tests, see xconfigtest.
+type AwsSsmClient interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+	GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+}
 
 // AwsSsmParamStore is used in config struct, it will load AWS_SSM_PARAM_STORE_PATH from ENV
 type AwsSsmParamStore struct {
 	Path string
 }
 
-// loadAwsSsmParamStore preload AWS SSM Param Store to memory
-func (l *loader) loadAwsSsmParamStore() error {
-	ctx := context.Background()
-	// aws client
+// loadAwsConfig returns the config set by SetAwsConfig, or try to use the default aws config.
+func loadAwsConfig(ctx context.Context) (aws.Config, error) {
 	if awsConfig == nil {
-		// try to get default aws config
 		cfg, err := config.LoadDefaultConfig(ctx)
 		if err != nil {
-			return fmt.Errorf("load default aws config failed: %w", err)
+			return aws.Config{}, fmt.Errorf("load default aws config failed: %w", err)
 		}
 		awsConfig = &cfg
 	}
-	client := ssm.NewFromConfig(*awsConfig)
+	return *awsConfig, nil
+}
 
-	// init
-	if l.AwsSsmParams == nil {
-		l.AwsSsmParams = make(map[string]string)
+// loadAwsSsmClient returns the client set by SetAwsSsmClient, or a client of the aws config.
+func loadAwsSsmClient(ctx context.Context) (AwsSsmClient, error) {
+	if awsSsmClient != nil {
+		return awsSsmClient, nil
+	}
+	cfg, err := loadAwsConfig(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return ssm.NewFromConfig(cfg), nil
+}
+
+type awsSsmSource struct {
+	path     string
+	mu       sync.RWMutex // refresh may run in background
+	params   map[string]string
+	versions map[string]int64
+}
+
+// AwsSsmSource looks up parameters under `path` in AWS SSM Param Store,
+// the name is the `ssm` tag or upper snake path like DB_PASSWORD.
+func AwsSsmSource(path string) Source {
+	return &awsSsmSource{path: path}
+}
+
+func (s *awsSsmSource) Name() string {
+	return "aws_ssm"
+}
+
+func (s *awsSsmSource) Lookup(field Field) (string, string, bool) {
+	key := field.Key("ssm", true)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.params[key]
+	return value, key, ok
+}
+
+func (s *awsSsmSource) Scan(field Field) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return scanKeys(mapKeys(s.params), field.Key("ssm", true))
+}
+
+// Keys lists the names of all parameters under the path
+func (s *awsSsmSource) Keys(prefix string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys []string
+	for k := range s.params {
+		if prefix == "" || strings.HasPrefix(k, strings.ToUpper(prefix)+"_") {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Preload AWS SSM Param Store to memory
+func (s *awsSsmSource) Preload(ctx context.Context) error {
+	_, err := s.Refresh(ctx)
+	return err
+}
+
+// Refresh fetches AWS SSM Param Store again, it is changed if any parameter version differs from the last fetch.
+func (s *awsSsmSource) Refresh(ctx context.Context) (bool, error) {
+	params, versions, err := s.fetch(ctx)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changed []string
+	for name, version := range versions {
+		if s.versions[name] != version {
+			changed = append(changed, name)
+		}
+	}
+	for name := range s.versions {
+		if _, ok := versions[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 && s.params != nil {
+		return false, nil
+	}
+	slog.Debug("aws ssm parameters changed", "path", s.path, "names", changed)
+	s.params = params
+	s.versions = versions
+	return true, nil
+}
+
+// fetch all parameters under the path, with values and versions
+func (s *awsSsmSource) fetch(ctx context.Context) (map[string]string, map[string]int64, error) {
+	// aws client
+	client, err := loadAwsSsmClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// init
+	params := make(map[string]string)
+	versions := make(map[string]int64)
 
 	// first request
 	res, err := client.GetParametersByPath(ctx, &ssm.GetParametersByPathInput{
-		Path:           aws.String(l.AwsSsmPath),
+		Path:           aws.String(s.path),
 		WithDecryption: aws.Bool(true),
 	})
 	// process then pagination
 	for {
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		for _, param := range res.Parameters {
 			if param.Name != nil && param.Value != nil {
-				name := strings.TrimPrefix(*param.Name, l.AwsSsmPath+"/")
-				l.AwsSsmParams[name] = *param.Value
+				name := strings.TrimPrefix(*param.Name, s.path+"/")
+				params[name] = *param.Value
+				versions[name] = param.Version
 			}
 		}
 		if res.NextToken != nil {
 			res, err = client.GetParametersByPath(ctx, &ssm.GetParametersByPathInput{
-				Path:           aws.String(l.AwsSsmPath),
+				Path:           aws.String(s.path),
 				WithDecryption: aws.Bool(true),
 				NextToken:      res.NextToken,
 			})
@@ -66,5 +173,5 @@ func (l *loader) loadAwsSsmParamStore() error {
 		}
 	}
 
-	return nil
+	return params, versions, nil
 }
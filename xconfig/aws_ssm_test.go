@@ -0,0 +1,119 @@
+package xconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/crestalnetwork/crestal-go-utils/xconfig/xconfigtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSsm replaces the aws ssm client with an in-memory fake until the test ends
+func fakeSsm(t *testing.T) *xconfigtest.Ssm {
+	fake := xconfigtest.NewSsm()
+	SetAwsSsmClient(fake)
+	t.Cleanup(func() { SetAwsSsmClient(nil) })
+	return fake
+}
+
+func Test_awsSsmSource_Preload(t *testing.T) {
+	type fields struct {
+		AwsSsmPath string
+		Name       string
+		Value      string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name: "normal",
+			fields: fields{
+				AwsSsmPath: "/testing/ci",
+				Name:       "FOO",
+				Value:      "bar",
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "secure string",
+			fields: fields{
+				AwsSsmPath: "/testing/ci",
+				Name:       "DB_PASSWORD",
+				Value:      "secret",
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "not in path",
+			fields: fields{
+				AwsSsmPath: "/testing/prod",
+				Name:       "FOO",
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "invalid path",
+			fields: fields{
+				AwsSsmPath: "testing/ci",
+			},
+			wantErr: assert.Error,
+		},
+	}
+	fake := fakeSsm(t)
+	fake.Put("/testing/ci/FOO", "bar")
+	fake.PutSecure("/testing/ci/DB_PASSWORD", "secret")
+	fake.Put("/testing/ci/nested/FOO", "nested")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &awsSsmSource{
+				path: tt.fields.AwsSsmPath,
+			}
+			tt.wantErr(t, s.Preload(context.Background()), fmt.Sprintf("Preload()"))
+			assert.Equal(t, tt.fields.Value, s.params[tt.fields.Name])
+		})
+	}
+}
+
+func TestAwsSsmSource(t *testing.T) {
+	type ssmConfig struct {
+		Service string
+		Token   string `ssm:"API_TOKEN"`
+		Hosts   map[string]string
+		DSN     string `expand:"true"`
+	}
+	fake := fakeSsm(t)
+	fake.PageSize = 2
+	fake.Put("/prod/app/SERVICE", "api")
+	fake.Put("/prod/app/API_TOKEN", "token")
+	fake.PutSecure("/prod/app/DSN", "mysql://root:${DB_PASSWORD}@db")
+	fake.PutSecure("/prod/shared/db_password", "secret")
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		fake.Put("/prod/app/HOSTS_"+name, strings.ToLower(name)+".local")
+	}
+	t.Setenv(AwsSsmParamStorePath, "/prod/app")
+	t.Setenv("DB_PASSWORD", "ssm:///prod/shared/db_password")
+
+	cfg := new(ssmConfig)
+	assert.NoError(t, Load(cfg))
+	assert.Equal(t, "api", cfg.Service)
+	assert.Equal(t, "token", cfg.Token)
+	assert.Len(t, cfg.Hosts, 5)
+	assert.Equal(t, "e.local", cfg.Hosts["e"])
+	assert.Equal(t, "mysql://root:secret@db", cfg.DSN)
+
+	// the versions are compared on refresh
+	s := AwsSsmSource("/prod/app").(*awsSsmSource)
+	assert.NoError(t, s.Preload(context.Background()))
+	changed, err := s.Refresh(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	fake.Put("/prod/app/SERVICE", "worker")
+	changed, err = s.Refresh(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "worker", s.params["SERVICE"])
+}
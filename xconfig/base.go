@@ -21,7 +21,7 @@ type Basic struct {
 	Debug   bool   `default:"false"`
 	Release string `default:"local-debug"` // github build number, injected in image by github action
 	// slack config is optional, if exists, it will send all warn/error log to slack
-	SlackToken   string
+	SlackToken   Secret
 	SlackChannel string `default:"C076H0HBZLZ"` // default is channel testnet-dev
 }
 
@@ -43,7 +43,7 @@ func (b Basic) GenLogger() *slog.Logger {
 				slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}),
 				slogslack.Option{
 					Level:    slog.LevelWarn,
-					BotToken: b.SlackToken,
+					BotToken: b.SlackToken.Reveal(),
 					Channel:  b.SlackChannel,
 				}.NewSlackHandler(),
 			))
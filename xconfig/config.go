@@ -1,33 +1,91 @@
-// Package xconfig can load config from shell env variables, aws ssm and docker/k8s secrets.
+// Package xconfig can load config from shell env variables, aws ssm, aws secrets manager and docker/k8s secrets.
 package xconfig
 
 import (
+	"context"
 	"os"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 )
 
-// SetAwsConfig if you want load config from aws ssm parameter store, you can set aws config manually.
-// If it is not set, it will try to use default aws config, only when AWS_SSM_PARAM_STORE_PATH is set .
+// SetAwsConfig if you want load config from aws ssm parameter store or secrets manager, you can set aws config manually.
+// If it is not set, it will try to use default aws config, only when AWS_SSM_PARAM_STORE_PATH or AWS_SECRETS_MANAGER_IDS is set.
 func SetAwsConfig(config *aws.Config) {
 	awsConfig = config
 }
 
+// SetAwsSsmClient replaces the aws ssm client made from the aws config, e.g. with the fake of xconfigtest in tests.
+// Set nil to restore the default.
+func SetAwsSsmClient(client AwsSsmClient) {
+	awsSsmClient = client
+}
+
+// SetAwsSecretsManagerClient replaces the aws secrets manager client made from the aws config,
+// it is used by AwsSecretsManagerSource without a client and asm:// references. Set nil to restore the default.
+func SetAwsSecretsManagerClient(client AwsSecretsManagerClient) {
+	awsAsmClient = client
+}
+
 // Load config to `dst` struct pointer from shell env variables and docker secrets.
+// If AWS_SSM_PARAM_STORE_PATH is set, aws ssm param store is used instead of docker secrets.
+// If AWS_SECRETS_MANAGER_IDS is set, these aws secrets manager secrets are used too, shell env overrides them.
 func Load(dst interface{}) error {
-	ssmPath := os.Getenv(AwsSsmParamStorePath)
+	return LoadContext(context.Background(), dst)
+}
+
+// LoadContext loads config like Load, the aws calls honour the ctx deadline and are retried on throttling and transient errors.
+// A source which failed to fetch is returned as a *SourceError.
+func LoadContext(ctx context.Context, dst interface{}, opts ...Option) error {
+	return defaultLoader(newOptions(opts)).LoadContext(ctx, dst, opts...)
+}
+
+// LoadAs loads config of type T like Load, the options can change the sources, names and validation.
+//
+//	settings, err := xconfig.LoadAs[Settings](xconfig.WithPrefix("AGENT"), xconfig.WithTimeout(10*time.Second))
+func LoadAs[T any](opts ...Option) (T, error) {
+	var dst T
+	err := LoadContext(context.Background(), &dst, opts...)
+	return dst, err
+}
+
+// MustLoadAs just same as LoadAs(), but it panics when an error occurs.
+func MustLoadAs[T any](opts ...Option) T {
+	dst, err := LoadAs[T](opts...)
+	if err != nil {
+		panic(err)
+	}
+	return dst
+}
+
+// Explain loads config like Load, and reports where every field value came from.
+func Explain(dst interface{}) (*Report, error) {
+	return defaultLoader(newOptions(nil)).Explain(dst)
+}
+
+// defaultLoader picks the sources by options and env variables
+func defaultLoader(o *options) *Loader {
+	if o.sources != nil {
+		return New(o.sources...)
+	}
+	var sources []Source
+	ssmPath := o.ssmPath
+	if ssmPath == "" {
+		ssmPath = os.Getenv(AwsSsmParamStorePath)
+	}
 	if ssmPath != "" {
-		err := LoadEnvAndAwsSsm(dst, ssmPath)
-		if err != nil {
-			return err
-		}
-		return nil
+		sources = append(sources, AwsSsmSource(ssmPath))
 	}
-	err := LoadEnvAndDockerSecret(dst)
-	if err != nil {
-		return err
+	if ids := os.Getenv(AwsSecretsManagerIds); ids != "" {
+		sources = append(sources, AwsSecretsManagerSource(nil, strings.Split(ids, ",")...))
 	}
-	return nil
+	sources = append(sources, EnvSource())
+	if o.secretPath != "" {
+		sources = append(sources, SecretSource(o.secretPath))
+	} else if ssmPath == "" {
+		sources = append(sources, SecretSource("/run/secrets"))
+	}
+	return New(sources...)
 }
 
 // MustLoad just same as Load(), but it panics when an error occurs.
@@ -39,39 +97,29 @@ func MustLoad(dst interface{}) {
 }
 
 // LoadEnvAndSecret load config to `dst` struct pointer from shell env variables and container secrets.
+//
+// Deprecated: use LoadAs[T](WithSources(EnvSource(), SecretSource(secretPath))).
 func LoadEnvAndSecret(dst interface{}, secretPath string) error {
-	l := loader{
-		Env:    true,
-		Secret: true,
-		Path:   secretPath,
-	}
-	return l.load(dst)
+	return New(EnvSource(), SecretSource(secretPath)).Load(dst)
 }
 
 // LoadEnvAndDockerSecret load config to `dst` struct pointer from shell env variables and docker secrets.
+//
+// Deprecated: use LoadAs[T](WithSources(EnvSource(), SecretSource("/run/secrets"))).
 func LoadEnvAndDockerSecret(dst interface{}) error {
 	return LoadEnvAndSecret(dst, "/run/secrets")
 }
 
 // LoadEnv load config to `dst` struct pointer from shell env variables only
+//
+// Deprecated: use LoadAs[T](WithSources(EnvSource())).
 func LoadEnv(dst interface{}) error {
-	l := loader{
-		Env:    true,
-		Secret: false,
-	}
-	return l.load(dst)
+	return New(EnvSource()).Load(dst)
 }
 
 // LoadEnvAndAwsSsm load config to `dst` struct pointer from shell env variables and aws ssm param store.
+//
+// Deprecated: use LoadAs[T](WithSources(AwsSsmSource(path), EnvSource())).
 func LoadEnvAndAwsSsm(dst interface{}, path string) error {
-	l := loader{
-		Env:        true,
-		AwsSsm:     true,
-		AwsSsmPath: path,
-	}
-	err := l.loadAwsSsmParamStore()
-	if err != nil {
-		return err
-	}
-	return l.load(dst)
+	return New(AwsSsmSource(path), EnvSource()).Load(dst)
 }
@@ -0,0 +1,52 @@
+package xconfig
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadAs(t *testing.T) {
+	type agentConfig struct {
+		Service string `default:"agent"`
+		Port    int    `env:"HTTP_PORT" validate:"min=1024"`
+		DSN     string `default:"postgres://${DB_PASSWORD}@db" expand:"true"`
+		DB      struct {
+			Password string
+		}
+		Chains map[string]string
+	}
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(path.Join(dir, "agent_db_password"), []byte("agent_pwd"), 0o600))
+	assert.NoError(t, os.WriteFile(path.Join(dir, "db_password"), []byte("other_pwd"), 0o600))
+	t.Setenv("SERVICE", "other")
+	t.Setenv("AGENT_HTTP_PORT", "8080")
+	t.Setenv("AGENT_CHAINS_BASE", "https://base.local")
+	t.Setenv("CHAINS_ETHEREUM", "https://ethereum.local")
+
+	// every name is prefixed
+	cfg, err := LoadAs[agentConfig](WithPrefix("AGENT"), WithSecretPath(dir))
+	assert.NoError(t, err)
+	assert.Equal(t, "agent", cfg.Service)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, "agent_pwd", cfg.DB.Password)
+	assert.Equal(t, "postgres://agent_pwd@db", cfg.DSN)
+	assert.Equal(t, map[string]string{"base": "https://base.local"}, cfg.Chains)
+
+	// validation
+	t.Setenv("AGENT_HTTP_PORT", "80")
+	_, err = LoadAs[agentConfig](WithPrefix("AGENT"), WithSources(EnvSource(), SecretSource(dir)))
+	assert.ErrorContains(t, err, "port (env:AGENT_HTTP_PORT, secret:agent_port): failed on validation min=1024")
+	cfg, err = LoadAs[agentConfig](WithPrefix("AGENT"), WithSources(EnvSource(), SecretSource(dir)), WithValidation(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 80, cfg.Port)
+
+	// without prefix
+	t.Setenv("HTTP_PORT", "8081")
+	cfg = MustLoadAs[agentConfig](WithSources(EnvSource(), SecretSource(dir)))
+	assert.Equal(t, "other", cfg.Service)
+	assert.Equal(t, "other_pwd", cfg.DB.Password)
+	assert.Panics(t, func() { MustLoadAs[string]() })
+}
@@ -0,0 +1,155 @@
+package xconfig
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocField describes a config field and the names the loader consults for it.
+type DocField struct {
+	// Path is the field path like "db.port"
+	Path string
+	// Type is the go type like "int"
+	Type      string
+	Env       string
+	Secret    string
+	Ssm       string
+	Default   string
+	Required  bool
+	Sensitive bool
+	// Desc is the `desc` tag
+	Desc string
+}
+
+// Doc lists every single value field of the config struct type of dst, with its env, secret file and ssm names.
+// Slices of structs and maps have dynamic keys, they are not listed.
+func Doc(dst interface{}) []DocField {
+	var fields []DocField
+	walkFields(reflect.TypeOf(dst), nil, func(f Field) {
+		fields = append(fields, DocField{
+			Path:      strings.Join(f.Path, "."),
+			Type:      f.Type.String(),
+			Env:       f.Key("env", true),
+			Secret:    f.Key("secret", false),
+			Ssm:       f.Key("ssm", true),
+			Default:   f.Tag.Get("default"),
+			Required:  f.Tag.Get("required") == "true" || hasValidateRule(f.Tag, "required"),
+			Sensitive: f.sensitive(),
+			Desc:      f.Tag.Get("desc"),
+		})
+	})
+	return fields
+}
+
+// WriteMarkdown writes a markdown reference table of the config struct type of dst.
+func WriteMarkdown(w io.Writer, dst interface{}) error {
+	var b strings.Builder
+	b.WriteString("| Field | Env | Secret File | SSM Parameter | Type | Default | Required | Description |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for _, f := range Doc(dst) {
+		desc := f.Desc
+		if f.Sensitive {
+			desc = strings.TrimSpace(desc + " (sensitive)")
+		}
+		required := ""
+		if f.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | `%s` | `%s` | %s | %s | %s | %s |\n",
+			f.Path, f.Env, f.Secret, f.Ssm, f.Type, markdownCode(f.Default), required, markdownEscape(desc))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteDotenvExample writes a .env.example of the config struct type of dst, sensitive defaults are left blank.
+func WriteDotenvExample(w io.Writer, dst interface{}) error {
+	var b strings.Builder
+	for _, f := range Doc(dst) {
+		comment := f.Desc
+		if f.Required {
+			comment = strings.TrimSpace(comment + " (required)")
+		}
+		if comment != "" {
+			fmt.Fprintf(&b, "# %s\n", comment)
+		}
+		value := f.Default
+		if f.Sensitive {
+			value = ""
+		}
+		fmt.Fprintf(&b, "%s=%s\n", f.Env, dotenvQuote(value))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteK8sManifest writes a skeleton of kubernetes container `env:` for the non-sensitive fields,
+// and a Secret named secretName for the sensitive ones, mount it at /run/secrets to make the files readable.
+func WriteK8sManifest(w io.Writer, dst interface{}, secretName string) error {
+	type envVar struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	}
+	type metadata struct {
+		Name string `yaml:"name"`
+	}
+	type secret struct {
+		APIVersion string            `yaml:"apiVersion"`
+		Kind       string            `yaml:"kind"`
+		Metadata   metadata          `yaml:"metadata"`
+		Type       string            `yaml:"type"`
+		StringData map[string]string `yaml:"stringData"`
+	}
+	envs := make([]envVar, 0)
+	s := secret{APIVersion: "v1", Kind: "Secret", Metadata: metadata{Name: secretName}, Type: "Opaque", StringData: make(map[string]string)}
+	for _, f := range Doc(dst) {
+		if f.Sensitive {
+			s.StringData[f.Secret] = ""
+		} else {
+			envs = append(envs, envVar{Name: f.Env, Value: f.Default})
+		}
+	}
+	envData, err := yaml.Marshal(map[string]interface{}{"env": envs})
+	if err != nil {
+		return err
+	}
+	secretData, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "# container env\n%s---\n# mount this secret at /run/secrets\n%s", envData, secretData)
+	return err
+}
+
+// hasValidateRule reports whether the `validate` tag has the rule
+func hasValidateRule(tag reflect.StructTag, rule string) bool {
+	for _, r := range strings.Split(tag.Get("validate"), ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// dotenvQuote quotes the value if it has spaces, quotes or comment chars
+func dotenvQuote(s string) string {
+	if strings.ContainsAny(s, " \t\n\"'#\\") {
+		return "'" + s + "'"
+	}
+	return s
+}
@@ -0,0 +1,50 @@
+package xconfig
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoc(t *testing.T) {
+	type docConfig struct {
+		Basic
+		Port int `default:"8080" desc:"http port"`
+		DB   struct {
+			Password string `required:"true" sensitive:"true" secret:"mysql_password"`
+			Host     string `validate:"required,hostname"`
+		}
+	}
+	fields := Doc(new(docConfig))
+	var db, password DocField
+	for _, f := range fields {
+		switch f.Path {
+		case "db.host":
+			db = f
+		case "db.password":
+			password = f
+		}
+	}
+	assert.Equal(t, DocField{Path: "db.host", Type: "string", Env: "DB_HOST", Secret: "db_host", Ssm: "DB_HOST", Required: true}, db)
+	assert.Equal(t, "mysql_password", password.Secret)
+	assert.True(t, password.Required)
+	assert.True(t, password.Sensitive)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteMarkdown(&buf, new(docConfig)))
+	assert.Contains(t, buf.String(), "| port | `PORT` | `port` | `PORT` | int | `8080` |  | http port |\n")
+	assert.Contains(t, buf.String(), "| db.password | `DB_PASSWORD` | `mysql_password` | `DB_PASSWORD` | string |  | yes | (sensitive) |\n")
+
+	buf.Reset()
+	assert.NoError(t, WriteDotenvExample(&buf, new(docConfig)))
+	assert.Contains(t, buf.String(), "# http port\nPORT=8080\n")
+	assert.Contains(t, buf.String(), "# (required)\nDB_PASSWORD=\n")
+
+	buf.Reset()
+	assert.NoError(t, WriteK8sManifest(&buf, new(docConfig), "app-secrets"))
+	assert.Contains(t, buf.String(), "    - name: PORT\n      value: \"8080\"\n")
+	assert.Contains(t, buf.String(), "name: app-secrets\n")
+	assert.Contains(t, buf.String(), "stringData:\n    mysql_password: \"\"\n")
+	assert.NotContains(t, buf.String(), "name: DB_PASSWORD")
+}
@@ -0,0 +1,162 @@
+package xconfig
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+type dotenvSource struct {
+	dir    string
+	values map[string]string
+}
+
+// DotenvSource looks up the dotenv files in dir: .env, .env.<env> and .env.local, a later file overrides the earlier ones.
+// The env is the ENV variable of shell or .env, "local" by default, the same as Basic.Env.
+// The key is the same as EnvSource, put it before EnvSource to make real env variables win.
+// The files are only parsed, the process environment is not changed.
+func DotenvSource(dir string) Source {
+	return &dotenvSource{dir: dir}
+}
+
+func (s *dotenvSource) Name() string {
+	return "dotenv"
+}
+
+func (s *dotenvSource) Lookup(field Field) (string, string, bool) {
+	if field.isStruct() {
+		return "", "", false
+	}
+	key := field.Key("env", true)
+	value, ok := s.values[key]
+	return value, key, ok
+}
+
+func (s *dotenvSource) Scan(field Field) []string {
+	return scanKeys(mapKeys(s.values), field.Key("env", true))
+}
+
+// Preload parses the dotenv files
+func (s *dotenvSource) Preload(_ context.Context) error {
+	values := make(map[string]string)
+	if err := readDotenv(path.Join(s.dir, ".env"), values); err != nil {
+		return err
+	}
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = values["ENV"]
+	}
+	if env == "" {
+		env = EnvLocal
+	}
+	if env != EnvLocal {
+		if err := readDotenv(path.Join(s.dir, ".env."+env), values); err != nil {
+			return err
+		}
+	}
+	if err := readDotenv(path.Join(s.dir, ".env.local"), values); err != nil {
+		return err
+	}
+	s.values = values
+	return nil
+}
+
+// readDotenv parses the file into values, a missing file is ignored
+func readDotenv(name string, values map[string]string) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := parseDotenv(f, values); err != nil {
+		return fmt.Errorf("parse %s failed: %w", name, err)
+	}
+	return nil
+}
+
+// parseDotenv supports `export` prefix, comments, single quoted literal values
+// and double quoted values with escapes, both quotes can span multiple lines.
+func parseDotenv(r io.Reader, values map[string]string) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("line %d: invalid format, it should be KEY=VALUE", lineNo)
+		}
+		value = strings.TrimSpace(value)
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[0]
+			value = value[1:]
+			start := lineNo
+			// read more lines until the closing quote
+			for {
+				if end := closingQuote(value, quote); end >= 0 {
+					value = value[:end]
+					break
+				}
+				if !scanner.Scan() {
+					return fmt.Errorf("line %d: unclosed quote", start)
+				}
+				lineNo++
+				value += "\n" + scanner.Text()
+			}
+			if quote == '"' {
+				value = unescapeDotenv(value)
+			}
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			// inline comment
+			value = strings.TrimSpace(value[:i])
+		}
+		values[key] = value
+	}
+	return scanner.Err()
+}
+
+// closingQuote returns the index of the closing quote, -1 if not found
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && quote == '"' {
+			i++ // skip the escaped char
+		} else if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
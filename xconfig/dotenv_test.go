@@ -0,0 +1,41 @@
+package xconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDotenvSource(t *testing.T) {
+	type dotenvConfig struct {
+		Greeting string
+		DB       struct {
+			User string
+			Name string
+			Port int
+			Host string `default:"localhost"`
+		}
+	}
+	t.Setenv("DB_HOST", "env_host")
+
+	cfg := new(dotenvConfig)
+	assert.NoError(t, New(DotenvSource("mock/dotenv"), EnvSource()).Load(cfg))
+	assert.Equal(t, "hello # not a comment", cfg.Greeting)
+	assert.Equal(t, "dev_user", cfg.DB.User)
+	assert.Equal(t, "multi\nline\tname", cfg.DB.Name)
+	assert.Equal(t, 3309, cfg.DB.Port)
+	assert.Equal(t, "env_host", cfg.DB.Host)
+
+	// shell env chooses the overlay
+	t.Setenv("ENV", "testnet-prod")
+	cfg = new(dotenvConfig)
+	assert.NoError(t, New(DotenvSource("mock/dotenv")).Load(cfg))
+	assert.Equal(t, "dotenv_user", cfg.DB.User)
+}
+
+func TestParseDotenvError(t *testing.T) {
+	values := make(map[string]string)
+	assert.Error(t, parseDotenv(strings.NewReader("A=1\nB='open"), values))
+	assert.Error(t, parseDotenv(strings.NewReader("NOT A PAIR"), values))
+}
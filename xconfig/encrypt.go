@@ -0,0 +1,181 @@
+package xconfig
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// EncryptedPrefix marks an encrypted value, like enc:v1:base64(ciphertext)
+const EncryptedPrefix = "enc:v1:"
+
+const (
+	// encryptionKeyEnv is the base64 AES key to decrypt values
+	encryptionKeyEnv = "CONFIG_ENCRYPTION_KEY"
+	// encryptionKeyFileEnv is the path of a file with the base64 AES key
+	encryptionKeyFileEnv = "CONFIG_ENCRYPTION_KEY_FILE"
+	// encryptionKeySecret is the secret file name of the key in the secret directory
+	encryptionKeySecret = "config_encryption_key"
+)
+
+// ErrNoEncryptionKey is returned when a value is encrypted but no key is configured.
+var ErrNoEncryptionKey = errors.New("no encryption key, set " + encryptionKeyEnv + " or use WithCipher")
+
+// Cipher encrypts and decrypts config values. AESCipher keeps the key in the app,
+// a KMS client can implement it to keep the key in the KMS.
+type Cipher interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+type aesCipher struct {
+	aead cipher.AEAD
+}
+
+// AESCipher encrypts with AES-GCM, the key is 16, 24 or 32 bytes, the ciphertext is the random nonce and the sealed data.
+func AESCipher(key []byte) (Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesCipher{aead: aead}, nil
+}
+
+func (c *aesCipher) Encrypt(_ context.Context, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, []byte(EncryptedPrefix)), nil
+}
+
+func (c *aesCipher) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < c.aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, sealed := ciphertext[:c.aead.NonceSize()], ciphertext[c.aead.NonceSize():]
+	return c.aead.Open(nil, nonce, sealed, []byte(EncryptedPrefix))
+}
+
+// ParseEncryptionKey decodes a base64 AES key, like the value of CONFIG_ENCRYPTION_KEY
+func ParseEncryptionKey(s string) (Cipher, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return AESCipher(key)
+}
+
+// EncryptValue encrypts a config value, the result has the enc:v1: prefix.
+func EncryptValue(ctx context.Context, c Cipher, value string) (string, error) {
+	ciphertext, err := c.Encrypt(ctx, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptValue decrypts a value with the enc:v1: prefix, other values are returned as is.
+func DecryptValue(ctx context.Context, c Cipher, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, EncryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	plaintext, err := c.Decrypt(ctx, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// isEncrypted reports whether the value has the enc:v1: prefix
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// defaultCipher finds the key in env CONFIG_ENCRYPTION_KEY, the file of CONFIG_ENCRYPTION_KEY_FILE,
+// or the secret file config_encryption_key in the roots, a later root overrides the earlier ones.
+// It returns ErrNoEncryptionKey if there is none.
+func defaultCipher(roots []string) (Cipher, error) {
+	if key := os.Getenv(encryptionKeyEnv); key != "" {
+		return ParseEncryptionKey(key)
+	}
+	var files []string
+	if file := os.Getenv(encryptionKeyFileEnv); file != "" {
+		files = []string{file}
+	} else {
+		for i := len(roots) - 1; i >= 0; i-- {
+			files = append(files, path.Join(roots[i], encryptionKeySecret))
+		}
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read encryption key failed: %w", err)
+		}
+		return ParseEncryptionKey(string(data))
+	}
+	return nil, ErrNoEncryptionKey
+}
+
+// secretRoots returns the roots of the secret sources of the loader and WithSecretPath, default /run/secrets
+func (st *loadState) secretRoots() []string {
+	var roots []string
+	for _, s := range st.loader.sources {
+		if ss, ok := s.(secretSource); ok {
+			roots = append(roots, ss.roots...)
+		}
+	}
+	if st.opts.secretPath != "" {
+		roots = append(roots, st.opts.secretPath)
+	}
+	if len(roots) == 0 {
+		roots = []string{"/run/secrets"}
+	}
+	return roots
+}
+
+// decrypt decrypts the value if it is encrypted, the plaintexts are cached for the dry run.
+// The default cipher is kept in the state, so a reload reads the key again.
+func (st *loadState) decrypt(value string) (string, error) {
+	if !isEncrypted(value) {
+		return value, nil
+	}
+	if v, ok := st.plain[value]; ok {
+		return v, nil
+	}
+	if st.cipher == nil {
+		c := st.opts.cipher
+		if c == nil {
+			var err error
+			if c, err = defaultCipher(st.secretRoots()); err != nil {
+				return "", err
+			}
+		}
+		st.cipher = c
+	}
+	plaintext, err := DecryptValue(st.ctx, st.cipher, value)
+	if err != nil {
+		return "", err
+	}
+	st.plain[value] = plaintext
+	return plaintext, nil
+}
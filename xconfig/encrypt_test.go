@@ -0,0 +1,109 @@
+package xconfig
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptedValues(t *testing.T) {
+	type encConfig struct {
+		Token string
+		DSN   string `default:"postgres://app:${DB_PASSWORD}@db" expand:"true"`
+		DB    struct {
+			Host     string
+			Password string
+		}
+	}
+	ctx := context.Background()
+	key := []byte("0123456789abcdef0123456789abcdef")
+	c, err := AESCipher(key)
+	assert.NoError(t, err)
+	encrypt := func(value string) string {
+		enc, err := EncryptValue(ctx, c, value)
+		assert.NoError(t, err)
+		return enc
+	}
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(path.Join(dir, ".env"), []byte("DB_HOST=db.local\nDB_PASSWORD="+encrypt("dotenv_pwd")+"\n"), 0o600))
+	t.Setenv("TOKEN", encrypt("env_token"))
+
+	cfg := new(encConfig)
+	report, err := New(DotenvSource(dir), EnvSource()).ExplainContext(ctx, cfg, WithCipher(c))
+	assert.NoError(t, err)
+	assert.Equal(t, "env_token", cfg.Token)
+	assert.Equal(t, "db.local", cfg.DB.Host)
+	assert.Equal(t, "dotenv_pwd", cfg.DB.Password)
+	assert.Equal(t, "postgres://app:dotenv_pwd@db", cfg.DSN)
+	// decrypted values are redacted in reports
+	assert.Contains(t, report.Fields, FieldReport{Path: "db.password", Source: "dotenv", Key: "DB_PASSWORD", Value: redacted, Sensitive: true})
+
+	// the key from env or a secret file
+	t.Setenv("DB_PASSWORD", encrypt("env_pwd"))
+	t.Setenv(encryptionKeyEnv, base64.StdEncoding.EncodeToString(key))
+	cfg = new(encConfig)
+	assert.NoError(t, New(EnvSource()).Load(cfg))
+	assert.Equal(t, "env_token", cfg.Token)
+	assert.Equal(t, "postgres://app:env_pwd@db", cfg.DSN)
+	t.Setenv(encryptionKeyEnv, "")
+	assert.NoError(t, os.WriteFile(path.Join(dir, encryptionKeySecret), []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+	cfg2, err := LoadAs[encConfig](WithSources(EnvSource()), WithSecretPath(dir))
+	assert.NoError(t, err)
+	assert.Equal(t, "env_token", cfg2.Token)
+
+	// no key or a wrong key
+	err = New(EnvSource()).Load(new(encConfig))
+	assert.ErrorIs(t, err, ErrNoEncryptionKey)
+	other, err := AESCipher([]byte("fedcba9876543210"))
+	assert.NoError(t, err)
+	_, err = LoadAs[encConfig](WithSources(EnvSource()), WithCipher(other))
+	assert.ErrorContains(t, err, "token (env:TOKEN): decrypt value from env TOKEN failed: cipher: message authentication failed")
+
+	// plain values pass through
+	value, err := DecryptValue(ctx, c, "plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", value)
+}
+
+func TestEncryptionKeyFile(t *testing.T) {
+	type keyConfig struct {
+		Token string
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	encrypt := func(key []byte, value string) string {
+		c, err := AESCipher(key)
+		assert.NoError(t, err)
+		enc, err := EncryptValue(ctx, c, value)
+		assert.NoError(t, err)
+		return enc
+	}
+	writeKey := func(key []byte) {
+		assert.NoError(t, os.WriteFile(path.Join(dir, encryptionKeySecret), []byte(base64.StdEncoding.EncodeToString(key)), 0o600))
+	}
+	key1, key2 := []byte("0123456789abcdef"), []byte("fedcba9876543210")
+	t.Setenv(encryptionKeyEnv, "")
+	t.Setenv("TOKEN", encrypt(key1, "token1"))
+	writeKey(key1)
+
+	// the key is found in the roots of the secret source, it is not an unused key
+	cfg := new(keyConfig)
+	assert.NoError(t, New(EnvSource(), SecretSource(t.TempDir(), dir)).LoadContext(ctx, cfg, WithStrict(true)))
+	assert.Equal(t, "token1", cfg.Token)
+
+	// a reload reads the rotated key
+	w, err := Watch[keyConfig](ctx, New(EnvSource(), SecretSource(dir)), 0)
+	assert.NoError(t, err)
+	t.Setenv("TOKEN", encrypt(key2, "token2"))
+	writeKey(key2)
+	assert.NoError(t, w.Reload(ctx))
+	assert.Eventually(t, func() bool {
+		return w.Get().Token == "token2"
+	}, 2*time.Second, 10*time.Millisecond)
+}
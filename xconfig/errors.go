@@ -0,0 +1,116 @@
+package xconfig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = validator.New(validator.WithRequiredStructEnabled())
+
+// FieldError is a config field which failed to load or validate.
+type FieldError struct {
+	// Path is the field path like "db.port"
+	Path string
+	// Keys are the keys consulted in sources, like "env:DB_PORT"
+	Keys []string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	if len(e.Keys) == 0 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s (%s): %s", e.Path, strings.Join(e.Keys, ", "), e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// LoadError aggregates all field errors of a loading, so a misconfigured deployment reports all problems at once.
+type LoadError struct {
+	Errors []*FieldError
+}
+
+func (e *LoadError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("load config failed with %d errors: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap supports errors.Is and errors.As on every field error
+func (e *LoadError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, fe := range e.Errors {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// SourceError is a source which failed to fetch its data, e.g. aws ssm is not reachable.
+type SourceError struct {
+	// Source is the source name like "aws_ssm", or the scheme of a reference like "ssm"
+	Source string
+	// Op is "preload", "refresh", or "resolve" for a reference
+	Op  string
+	Err error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("%s %s source failed: %s", e.Op, e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// UnusedKeysError is the keys in sources which are not used by any field, in strict mode.
+type UnusedKeysError struct {
+	// Keys are like "aws_ssm:DB_PASWORD"
+	Keys []string
+}
+
+func (e *UnusedKeysError) Error() string {
+	return fmt.Sprintf("%d config keys are not used by any field: %s", len(e.Keys), strings.Join(e.Keys, ", "))
+}
+
+// ErrRequired is the cause of FieldError when a `required:"true"` field is blank
+var ErrRequired = errors.New("is required")
+
+// ErrDeprecatedKey is the cause of FieldError when a deprecated alias supplied the value, with WithStrictAliases
+var ErrDeprecatedKey = errors.New("deprecated key is used")
+
+// validateStruct runs the `validate` tags on dst, appends failures to the state
+func (st *loadState) validateStruct(validate *validator.Validate, dst interface{}) {
+	err := validate.Struct(dst)
+	var ves validator.ValidationErrors
+	if !errors.As(err, &ves) {
+		return
+	}
+	for _, ve := range ves {
+		// remove the root struct name
+		ns := ve.StructNamespace()
+		if i := strings.Index(ns, "."); i >= 0 {
+			ns = ns[i+1:]
+		}
+		fe := &FieldError{Path: ns}
+		if info, ok := st.fields[ns]; ok {
+			if st.failed(info.path) {
+				continue // already reported
+			}
+			fe.Path = info.path
+			fe.Keys = info.keys
+		}
+		if ve.Param() != "" {
+			fe.Err = fmt.Errorf("failed on validation %s=%s", ve.Tag(), ve.Param())
+		} else {
+			fe.Err = fmt.Errorf("failed on validation %s", ve.Tag())
+		}
+		st.errs = append(st.errs, fe)
+	}
+}
@@ -0,0 +1,193 @@
+package xconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// maxExpandDepth limits the nested interpolation, to detect cycles
+const maxExpandDepth = 10
+
+// refResolvers resolve the indirect reference values by url scheme
+var refResolvers = map[string]func(ctx context.Context, ref *url.URL) (string, error){
+	"file": resolveFileRef,
+	"ssm":  resolveSsmRef,
+	"asm":  resolveAsmRef,
+}
+
+// remoteRefs are fetched with retries like the remote sources
+var remoteRefs = map[string]bool{"ssm": true, "asm": true}
+
+// expandValue interpolates ${NAME} in value, then resolves it if it is an indirect reference.
+func (st *loadState) expandValue(value string) (string, error) {
+	if strings.Contains(value, "${") {
+		var err error
+		if value, err = st.interpolate(value, 0); err != nil {
+			return "", err
+		}
+	}
+	return st.resolveRef(value)
+}
+
+// interpolate replaces ${NAME} with the value of the field whose env key is NAME, or the env variable NAME.
+// The variable is interpolated and resolved too.
+// $${ is an escaped ${.
+func (st *loadState) interpolate(value string, depth int) (string, error) {
+	if depth > maxExpandDepth {
+		return "", errors.New("interpolation is too deep, maybe there is a cycle")
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(value, "${")
+		if i < 0 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		if i > 0 && value[i-1] == '$' {
+			// escaped
+			b.WriteString(value[:i-1] + "${")
+			value = value[i+2:]
+			continue
+		}
+		end := strings.IndexByte(value[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed ${ in %q", value)
+		}
+		name := value[i+2 : i+end]
+		v, ok := st.variables()[name]
+		if !ok {
+			v, ok = os.LookupEnv(name)
+		}
+		if !ok {
+			return "", fmt.Errorf("unresolved reference ${%s}", name)
+		}
+		if strings.Contains(v, "${") {
+			var err error
+			if v, err = st.interpolate(v, depth+1); err != nil {
+				return "", err
+			}
+		}
+		// the variable can be a reference too
+		v, err := st.resolveRef(v)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value[:i] + v)
+		value = value[i+end+1:]
+	}
+}
+
+// variables returns the raw values of all fields by env key, for interpolation.
+// They are collected by a dry run loading, so a field can refer to the later ones.
+func (st *loadState) variables() map[string]string {
+	if st.vars == nil {
+		dry := st.loader.newLoadState(st.ctx, st.root, st.opts)
+		dry.noExpand = true
+		dry.plain = st.plain
+		dry.cipher = st.cipher
+		st.loader.loadStruct(reflect.New(st.root).Interface(), dry, "")
+		st.vars = dry.raw
+	}
+	return st.vars
+}
+
+// resolveRef resolves the value if it is an indirect reference like file:///path, ssm:///name or asm://secret#key
+func (st *loadState) resolveRef(value string) (string, error) {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return value, nil
+	}
+	resolver, ok := refResolvers[scheme]
+	if !ok {
+		return value, nil
+	}
+	if v, ok := st.refs[value]; ok {
+		return v, nil
+	}
+	ref, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference %s: %w", value, err)
+	}
+	var v string
+	resolve := func(ctx context.Context) (err error) {
+		v, err = resolver(ctx, ref)
+		return err
+	}
+	if remoteRefs[scheme] {
+		err = st.opts.fetch(st.ctx, scheme, "resolve", resolve)
+	} else {
+		err = resolve(st.ctx)
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolve reference %s failed: %w", value, err)
+	}
+	st.refs[value] = v
+	return v, nil
+}
+
+// resolveFileRef reads the file like file:///run/secrets/db_password
+func resolveFileRef(_ context.Context, ref *url.URL) (string, error) {
+	data, err := os.ReadFile(ref.Host + ref.Path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// resolveSsmRef gets the aws ssm parameter like ssm:///prod/db/password
+func resolveSsmRef(ctx context.Context, ref *url.URL) (string, error) {
+	client, err := loadAwsSsmClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	res, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(ref.Host + ref.Path),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(res.Parameter.Value), nil
+}
+
+// resolveAsmRef gets the aws secrets manager secret like asm://prod/db#password,
+// the fragment is the key of a JSON object secret, the whole secret is used without it.
+func resolveAsmRef(ctx context.Context, ref *url.URL) (string, error) {
+	client, err := loadAwsAsmClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	res, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(ref.Host + ref.Path),
+	})
+	if err != nil {
+		return "", err
+	}
+	secret := aws.ToString(res.SecretString)
+	if ref.Fragment == "" {
+		return secret, nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &obj); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+	v, ok := obj[ref.Fragment]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in secret", ref.Fragment)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	data, _ := json.Marshal(v)
+	return string(data), nil
+}
@@ -0,0 +1,84 @@
+package xconfig
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// initialisms split an upper case run of a plural acronym into words, like RPCURLs into RPC and URLs
+var initialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP", "HTTPS", "ID", "IP", "JSON", "JWT",
+	"KMS", "QPS", "RAM", "RPC", "SLA", "SMTP", "SQL", "SSH", "SSM", "TCP", "TLS", "TTL", "UDP", "UI", "UID", "UUID",
+	"URI", "URL", "UTF8", "VM", "WS", "XML", "XMPP", "XSRF", "XSS",
+}
+
+// snakeName is the snake name of a struct field, like strcase.ToSnake,
+// except that plural acronyms are kept as words, e.g. RPCURLs is rpc_urls and HostIPs is host_ips, instead of rpcur_ls.
+func snakeName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		j := i
+		for j < len(name) && name[j] >= 'A' && name[j] <= 'Z' {
+			j++
+		}
+		// an upper case run followed by the plural s at the end of a word
+		if j-i >= 2 && j < len(name) && name[j] == 's' && (j+1 == len(name) || name[j+1] < 'a' || name[j+1] > 'z') {
+			b.WriteString(acronymWords(name[i:j]) + "s")
+			i = j + 1
+			continue
+		}
+		if j == i {
+			j++
+		}
+		b.WriteString(name[i:j])
+		i = j
+	}
+	return strcase.ToSnake(b.String())
+}
+
+// acronymWords splits an upper case run by the initialisms into title case words like RpcUrl,
+// the run is one word if it can't be split.
+func acronymWords(run string) string {
+	var words []string
+	for rest := run; rest != ""; {
+		var word string
+		for _, w := range initialisms {
+			if strings.HasPrefix(rest, w) && len(w) > len(word) {
+				word = w
+			}
+		}
+		if word == "" {
+			return run[:1] + strings.ToLower(run[1:])
+		}
+		words = append(words, word[:1]+strings.ToLower(word[1:]))
+		rest = rest[len(word):]
+	}
+	return strings.Join(words, "")
+}
+
+// walkFields calls fn for every single value field of struct type t, with the same path as the loader.
+// Slices of structs and maps have dynamic keys, they are skipped.
+func walkFields(t reflect.Type, prefixes []string, fn func(f Field)) {
+	t = indirectType(t)
+	for i := 0; i < t.NumField(); i++ {
+		fieldStruct := t.Field(i)
+		if !fieldStruct.IsExported() {
+			continue
+		}
+		if !isScalarType(fieldStruct.Type) {
+			walkFields(fieldStruct.Type, fieldNamePath(prefixes, &fieldStruct), fn)
+			continue
+		}
+		ft := indirectType(fieldStruct.Type)
+		if ft.Kind() == reflect.Map || (ft.Kind() == reflect.Slice && !isScalarType(ft.Elem())) {
+			continue
+		}
+		fn(Field{
+			Path: append(prefixes[:len(prefixes):len(prefixes)], snakeName(fieldStruct.Name)),
+			Tag:  fieldStruct.Tag,
+			Type: fieldStruct.Type,
+		})
+	}
+}
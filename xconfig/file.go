@@ -0,0 +1,119 @@
+package xconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"gopkg.in/yaml.v3"
+)
+
+type fileSource struct {
+	name   string
+	values map[string]string
+}
+
+// FileSource looks up a YAML or JSON config file like config.yaml, and its env overlay like config.testnet-prod.yaml,
+// the overlay overrides the base file. The env is the ENV variable of shell or the base file, "local" by default.
+// Nested keys are joined by "_" in snake case, e.g. `db: {password: x}` gives db_password,
+// the key is the `file` tag or lower snake path like db_password. Put it before EnvSource to make env variables win.
+// A missing file is ignored.
+func FileSource(name string) Source {
+	return &fileSource{name: name}
+}
+
+func (s *fileSource) Name() string {
+	return "file"
+}
+
+func (s *fileSource) Lookup(field Field) (string, string, bool) {
+	if field.isStruct() {
+		return "", "", false
+	}
+	key := field.Key("file", false)
+	value, ok := s.values[key]
+	return value, key, ok
+}
+
+func (s *fileSource) Scan(field Field) []string {
+	return scanKeys(mapKeys(s.values), field.Key("file", false))
+}
+
+// Preload parses the config file and its overlay
+func (s *fileSource) Preload(_ context.Context) error {
+	values := make(map[string]string)
+	if err := readConfigFile(s.name, values); err != nil {
+		return err
+	}
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = values["env"]
+	}
+	if env == "" {
+		env = EnvLocal
+	}
+	ext := filepath.Ext(s.name)
+	overlay := strings.TrimSuffix(s.name, ext) + "." + env + ext
+	if err := readConfigFile(overlay, values); err != nil {
+		return err
+	}
+	s.values = values
+	return nil
+}
+
+// readConfigFile parses the YAML or JSON file into flat values, a missing file is ignored
+func readConfigFile(name string, values map[string]string) error {
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse %s failed: %w", name, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil // empty file
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("parse %s failed: the root should be a mapping", name)
+	}
+	return flattenYAML(values, "", doc.Content[0])
+}
+
+// flattenYAML writes the values of mapping node into dst with snake case keys,
+// nested mappings and sequences are also written as YAML, they can be loaded into map or slice fields.
+func flattenYAML(dst map[string]string, prefix string, node *yaml.Node) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := strcase.ToSnake(node.Content[i].Value)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		value := node.Content[i+1]
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+		switch value.Kind {
+		case yaml.ScalarNode:
+			if value.Tag != "!!null" {
+				dst[key] = value.Value
+			}
+		case yaml.MappingNode:
+			if err := flattenYAML(dst, key, value); err != nil {
+				return err
+			}
+			fallthrough
+		default:
+			data, err := yaml.Marshal(value)
+			if err != nil {
+				return err
+			}
+			dst[key] = string(data)
+		}
+	}
+	return nil
+}
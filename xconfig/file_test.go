@@ -0,0 +1,43 @@
+package xconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSource(t *testing.T) {
+	type fileConfig struct {
+		Name  string `file:"app_name"`
+		Hosts []string
+		DB    struct {
+			Host string
+			Port int
+			User string
+		}
+		RPCURLs map[string]string `file:"rpc_urls"`
+	}
+	t.Setenv("DB_USER", "env_user")
+
+	cfg := new(fileConfig)
+	assert.NoError(t, New(FileSource("mock/config.yaml"), EnvSource()).Load(cfg))
+	assert.Equal(t, "file_app", cfg.Name)
+	assert.Equal(t, []string{"a.local", "b.local"}, cfg.Hosts)
+	assert.Equal(t, "db.local", cfg.DB.Host)
+	assert.Equal(t, 5432, cfg.DB.Port)
+	assert.Equal(t, "env_user", cfg.DB.User)
+	assert.Equal(t, map[string]string{"ethereum": "https://eth.local"}, cfg.RPCURLs)
+
+	// env overlay
+	t.Setenv("ENV", "testnet-dev")
+	cfg = new(fileConfig)
+	assert.NoError(t, New(FileSource("mock/config.yaml")).Load(cfg))
+	assert.Equal(t, "db.dev", cfg.DB.Host)
+	assert.Equal(t, 5432, cfg.DB.Port)
+
+	// json
+	cfg = new(fileConfig)
+	assert.NoError(t, New(FileSource("mock/config.json")).Load(cfg))
+	assert.Equal(t, "db.json", cfg.DB.Host)
+	assert.Equal(t, 5433, cfg.DB.Port)
+}
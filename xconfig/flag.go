@@ -0,0 +1,98 @@
+package xconfig
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+)
+
+// FlagSource is a source of command line flags generated from a config struct.
+// Put it as the last source, so the flags override everything.
+type FlagSource struct {
+	set    *flag.FlagSet
+	values map[string]*flagValue
+}
+
+// Flags generates a flag set from the config struct type of dst, one flag for every field.
+// The flag name is the env name in lower kebab case like --db-password, or the `flag` tag,
+// the usage is the `desc` tag with the env name, the default is the `default` tag.
+// Call Parse before loading.
+func Flags(name string, dst interface{}) *FlagSource {
+	s := &FlagSource{
+		set:    flag.NewFlagSet(name, flag.ContinueOnError),
+		values: make(map[string]*flagValue),
+	}
+	walkFields(reflect.TypeOf(dst), nil, func(f Field) {
+		name := flagName(f)
+		value := &flagValue{isBool: indirectType(f.Type).Kind() == reflect.Bool}
+		usage := f.Tag.Get("desc")
+		if env := f.Key("env", true); usage != "" {
+			usage += " (env " + env + ")"
+		} else {
+			usage = "env " + env
+		}
+		s.set.Var(value, name, usage)
+		if def := f.Tag.Get("default"); def != "" {
+			s.set.Lookup(name).DefValue = def
+		}
+		s.values[name] = value
+	})
+	return s
+}
+
+// FlagSet returns the generated flag set, to add more flags or change the usage.
+func (s *FlagSource) FlagSet() *flag.FlagSet {
+	return s.set
+}
+
+// Parse parses the command line arguments without the program name, like os.Args[1:].
+// It returns flag.ErrHelp if -h or --help is set, the usage is printed already.
+func (s *FlagSource) Parse(args []string) error {
+	return s.set.Parse(args)
+}
+
+func (s *FlagSource) Name() string {
+	return "flag"
+}
+
+func (s *FlagSource) Lookup(field Field) (string, string, bool) {
+	name := flagName(field)
+	value, ok := s.values[name]
+	if !ok || !value.set {
+		return "", "--" + name, false
+	}
+	return value.value, "--" + name, true
+}
+
+// flagName is the `flag` tag, or the env key without prefix in lower kebab case
+func flagName(f Field) string {
+	if name := f.Tag.Get("flag"); name != "" {
+		return name
+	}
+	return strings.ReplaceAll(strings.ToLower(f.unprefixed().Key("env", true)), "_", "-")
+}
+
+// flagValue keeps the raw flag value, it is parsed by the loader like other sources
+type flagValue struct {
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	v.set = true
+	return nil
+}
+
+// IsBoolFlag allows bool fields to be set without value, like --debug
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
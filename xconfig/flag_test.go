@@ -0,0 +1,42 @@
+package xconfig
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlags(t *testing.T) {
+	type flagConfig struct {
+		Debug   bool          `desc:"enable debug log"`
+		Timeout time.Duration `default:"30s" desc:"request timeout"`
+		DB      struct {
+			Host string `default:"localhost"`
+			Port int    `env:"MYSQL_DB_PORT" flag:"port"`
+		}
+		Chains map[string]string
+	}
+	t.Setenv("DB_HOST", "env_host")
+	t.Setenv("TIMEOUT", "10s")
+
+	cfg := new(flagConfig)
+	flags := Flags("test", cfg)
+	assert.NoError(t, flags.Parse([]string{"--debug", "--db-host", "flag_host", "-port=3307"}))
+	assert.NoError(t, New(EnvSource(), flags).Load(cfg))
+	assert.True(t, cfg.Debug)
+	assert.Equal(t, 10*time.Second, cfg.Timeout)
+	assert.Equal(t, "flag_host", cfg.DB.Host)
+	assert.Equal(t, 3307, cfg.DB.Port)
+
+	// help lists the env names
+	var buf bytes.Buffer
+	flags = Flags("test", cfg)
+	flags.FlagSet().SetOutput(&buf)
+	assert.ErrorIs(t, flags.Parse([]string{"--help"}), flag.ErrHelp)
+	assert.Contains(t, buf.String(), "request timeout (env TIMEOUT) (default 30s)")
+	assert.Contains(t, buf.String(), "env MYSQL_DB_PORT")
+	assert.NotContains(t, buf.String(), "chains")
+}
@@ -1,115 +1,240 @@
 package xconfig
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
-	"os"
-	"path"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
-
-	"github.com/iancoleman/strcase"
-	"gopkg.in/yaml.v3"
 )
 
-type loader struct {
-	Env          bool
-	Secret       bool
-	Path         string
-	AwsSsm       bool
-	AwsSsmPath   string
-	AwsSsmParams map[string]string
+// Loader loads config into a struct from a stack of sources, later sources take precedence over earlier ones.
+// The `default` struct tag is always the lowest precedence.
+type Loader struct {
+	sources []Source
 }
 
-// load config to struct pointer
-// prefixes are parents names path, for recursive calling
-func (l *loader) load(dst interface{}, prefixes ...string) error {
-	configValue := reflect.Indirect(reflect.ValueOf(dst))
-	if configValue.Kind() != reflect.Struct {
-		return errors.New("invalid dst, it should be a struct pointer")
+// New creates a Loader with sources, a value found in a later source overrides the earlier ones.
+func New(sources ...Source) *Loader {
+	return &Loader{sources: sources}
+}
+
+// Load config to `dst` struct pointer, then runs the `validate` tags.
+// All blank required fields, invalid values and validation failures are returned together as a *LoadError.
+func (l *Loader) Load(dst interface{}) error {
+	return l.LoadContext(context.Background(), dst)
+}
+
+// LoadContext loads config like Load, the remote sources are fetched with ctx and retried on transient errors.
+// A source which failed to fetch is returned as a *SourceError.
+func (l *Loader) LoadContext(ctx context.Context, dst interface{}, opts ...Option) error {
+	_, err := l.ExplainContext(ctx, dst, opts...)
+	return err
+}
+
+// Explain loads config to `dst` struct pointer like Load, and reports where every field value came from.
+// The report is returned even if loading fails, for debugging.
+func (l *Loader) Explain(dst interface{}) (*Report, error) {
+	return l.ExplainContext(context.Background(), dst)
+}
+
+// ExplainContext is Explain with a context and options like LoadContext.
+func (l *Loader) ExplainContext(ctx context.Context, dst interface{}, opts ...Option) (*Report, error) {
+	o := newOptions(opts)
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	if err := l.preload(ctx, o); err != nil {
+		return nil, err
+	}
+	return l.load(ctx, dst, o)
+}
+
+// preload fetches the data of remote sources
+func (l *Loader) preload(ctx context.Context, o *options) error {
+	for _, s := range l.sources {
+		if p, ok := s.(Preloader); ok {
+			if err := o.fetch(ctx, s.Name(), "preload", p.Preload); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// refresh fetches the remote sources again, returns true if any of them may have changed
+func (l *Loader) refresh(ctx context.Context, o *options) (bool, error) {
+	var changed bool
+	for _, s := range l.sources {
+		if r, ok := s.(Refresher); ok {
+			err := o.fetch(ctx, s.Name(), "refresh", func(ctx context.Context) error {
+				c, err := r.Refresh(ctx)
+				changed = changed || c
+				return err
+			})
+			if err != nil {
+				return false, err
+			}
+		} else if p, ok := s.(Preloader); ok {
+			if err := o.fetch(ctx, s.Name(), "preload", p.Preload); err != nil {
+				return false, err
+			}
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// loadState collects the results of a loading
+type loadState struct {
+	ctx    context.Context
+	loader *Loader
+	root   reflect.Type // the struct type of dst
+	opts   *options
+
+	fields map[string]*fieldInfo // by struct namespace like "DB.Port", to find the validation errors
+	errs   []*FieldError
+	report []FieldReport
+
+	noExpand bool              // skip interpolation and references, for the dry run
+	raw      map[string]string // raw values by env key
+	vars     map[string]string // variables for interpolation, collected lazily
+	refs     map[string]string // resolved references cache
+	plain    map[string]string // decrypted values cache
+	cipher   Cipher            // decrypts the values, found on the first encrypted value
+	used     map[string]bool   // keys found in sources like "env:DB_PORT", for the strict mode
+}
+
+type fieldInfo struct {
+	path string   // like "db.port"
+	keys []string // keys consulted, like "env:DB_PORT"
+}
+
+func (l *Loader) newLoadState(ctx context.Context, root reflect.Type, o *options) *loadState {
+	return &loadState{
+		ctx:    ctx,
+		loader: l,
+		root:   root,
+		opts:   o,
+		fields: make(map[string]*fieldInfo),
+		raw:    make(map[string]string),
+		refs:   make(map[string]string),
+		plain:  make(map[string]string),
+		used:   make(map[string]bool),
+	}
+}
+
+// child creates a state for probing, it shares the context and caches
+func (st *loadState) child() *loadState {
+	c := st.loader.newLoadState(st.ctx, st.root, st.opts)
+	c.noExpand = st.noExpand
+	c.vars = st.vars
+	c.refs = st.refs
+	c.plain = st.plain
+	c.cipher = st.cipher
+	c.used = st.used
+	return c
+}
+
+// failed reports whether the field path already has an error
+func (st *loadState) failed(path string) bool {
+	for _, fe := range st.errs {
+		if fe.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// load config to struct pointer and validate it
+func (l *Loader) load(ctx context.Context, dst interface{}, o *options) (*Report, error) {
+	if reflect.Indirect(reflect.ValueOf(dst)).Kind() != reflect.Struct {
+		return nil, errors.New("invalid dst, it should be a struct pointer")
+	}
+	st := l.newLoadState(ctx, reflect.Indirect(reflect.ValueOf(dst)).Type(), o)
+	l.loadStruct(dst, st, "")
+	if o.validate != nil {
+		st.validateStruct(o.validate, dst)
 	}
+	report := &Report{Fields: st.report}
+	if o.strict {
+		report.Unused = l.unusedKeys(st)
+	}
+	if len(st.errs) > 0 {
+		return report, &LoadError{Errors: st.errs}
+	}
+	if len(report.Unused) > 0 {
+		for _, key := range report.Unused {
+			slog.Warn("config key is not used by any field, maybe a typo", "key", key)
+		}
+		if o.failUnused {
+			return report, &UnusedKeysError{Keys: report.Unused}
+		}
+	}
+	return report, nil
+}
 
+// unusedKeys returns the keys of Lister sources which are not found by any field, like "aws_ssm:DB_PASWORD"
+func (l *Loader) unusedKeys(st *loadState) []string {
+	var unused []string
+	for _, s := range l.sources {
+		lister, ok := s.(Lister)
+		if !ok {
+			continue
+		}
+		keys := lister.Keys(st.opts.prefix)
+		sort.Strings(keys)
+		for _, k := range keys {
+			if key := s.Name() + ":" + k; !st.used[key] {
+				unused = append(unused, key)
+			}
+		}
+	}
+	return unused
+}
+
+// loadStruct loads config to struct pointer
+// ns is the struct namespace like "DB", prefixes are parents names path, for recursive calling
+func (l *Loader) loadStruct(dst interface{}, st *loadState, ns string, prefixes ...string) {
+	configValue := reflect.Indirect(reflect.ValueOf(dst))
 	configType := configValue.Type()
 	for i := 0; i < configType.NumField(); i++ {
 		var fieldStruct = configType.Field(i)
 		var field = configValue.Field(i)
-		var value string
-		var source string // for debug
 
 		if !field.CanAddr() || !field.CanInterface() {
 			continue
 		}
 
-		// check default value first
-		defaultValue := fieldStruct.Tag.Get("default")
-		if defaultValue != "" {
-			value = defaultValue
-			source = "default"
-		}
-		// check aws ssm
-		if l.AwsSsm && l.AwsSsmParams != nil {
-			ssmName := fieldStruct.Tag.Get("ssm")
-			if ssmName == "" {
-				ssmName = strings.ToUpper(strings.Join(append(prefixes, strcase.ToSnake(fieldStruct.Name)), "_"))
-			}
-			if v, ok := l.AwsSsmParams[ssmName]; ok && v != "" {
-				value = v
-				source = "aws_ssm"
-			}
-		}
-		// check shell env
-		if l.Env && reflect.Indirect(field).Kind() != reflect.Struct {
-			envName := fieldStruct.Tag.Get("env")
-			if envName == "" {
-				envName = strings.ToUpper(strings.Join(append(prefixes, strcase.ToSnake(fieldStruct.Name)), "_"))
-			}
-			envValue := os.Getenv(envName)
-			if envValue != "" {
-				value = envValue
-				source = "env"
-			}
-		}
-		// check secret
-		if l.Secret {
-			secretName := fieldStruct.Tag.Get("secret")
-			if secretName == "" {
-				secretName = strings.Join(append(prefixes, strcase.ToSnake(fieldStruct.Name)), "_")
-			}
-			secretValue := l.getSecret(secretName)
-			if secretValue != "" {
-				value = secretValue
-				source = "secret"
-			}
-		}
-		// load value to field
-		isBlank := reflect.DeepEqual(field.Interface(), reflect.Zero(field.Type()).Interface())
-		if isBlank && value != "" {
-			slog.Debug("Loading configuration", "field", fieldStruct.Name, "source", source)
-			switch reflect.Indirect(field).Kind() {
-			case reflect.Bool:
-				switch strings.ToLower(value) {
-				case "", "0", "f", "false":
-					field.Set(reflect.ValueOf(false))
-				default:
-					field.Set(reflect.ValueOf(true))
-				}
-			case reflect.String:
-				field.Set(reflect.ValueOf(value))
-			default:
-				if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
-					return err
-				}
-			}
+		f := Field{
+			Path:   append(prefixes[:len(prefixes):len(prefixes)], snakeName(fieldStruct.Name)),
+			Tag:    fieldStruct.Tag,
+			Type:   fieldStruct.Type,
+			prefix: st.opts.prefix,
 		}
+		res := l.loadValue(field, f, st, ns+fieldStruct.Name)
 
-		// return error if it is required but blank
-		if isBlank && value == "" && fieldStruct.Tag.Get("required") == "true" {
-			return errors.New(fieldStruct.Name + " is required")
+		// an optional section is a nil named struct pointer, it stays nil unless any of its fields is configured,
+		// an embedded pointer is always allocated, its fields are promoted
+		if field.Kind() == reflect.Ptr && field.IsNil() && field.Type().Elem().Kind() == reflect.Struct &&
+			!isScalarType(field.Type()) && !fieldStruct.Anonymous && f.Tag.Get("section") != "always" {
+			section := reflect.New(field.Type().Elem())
+			sectionSt := st.child()
+			l.loadStruct(section.Interface(), sectionSt, ns+fieldStruct.Name+".", fieldNamePath(prefixes, &fieldStruct)...)
+			if sectionSt.configured() {
+				field.Set(section)
+				st.merge(sectionSt)
+			}
+			continue
 		}
 
 		// recursive struct and slice
-		for field.Kind() == reflect.Ptr {
+		for field.Kind() == reflect.Ptr && !isScalarType(field.Type()) {
 			if field.IsNil() {
 				field.Set(reflect.New(field.Type().Elem()))
 			}
@@ -117,59 +242,293 @@ func (l *loader) load(dst interface{}, prefixes ...string) error {
 			field = field.Elem()
 		}
 
+		// a slice of single values can be set by indexed keys like HOSTS_0, they are reported as entries
+		if isDelimitedSlice(field.Type()) && (res.source == "" || res.source == "default") &&
+			l.loadSlice(field, f, st, ns+fieldStruct.Name, res.source == "default") {
+			continue
+		}
+
+		// report the leaf fields, structs are reported by their fields, and maps by their entries unless set as a whole
+		isContainer := (field.Kind() == reflect.Slice && !isScalarType(field.Type().Elem())) ||
+			(field.Kind() == reflect.Map && res.source == "")
+		if isScalarType(field.Type()) && !isContainer {
+			st.reportField(field, f, res)
+		}
+
+		childNs := ns + fieldStruct.Name
+		if isScalarType(field.Type()) && field.Kind() != reflect.Slice && field.Kind() != reflect.Map {
+			continue
+		}
 		switch field.Kind() {
 		case reflect.Struct:
-			if err := l.load(field.Addr().Interface(), fieldNamePath(prefixes, &fieldStruct)...); err != nil {
-				return err
-			}
+			l.loadStruct(field.Addr().Interface(), st, childNs+".", fieldNamePath(prefixes, &fieldStruct)...)
+		case reflect.Map:
+			l.loadMap(field, f, st, childNs)
 		case reflect.Slice:
+			elemType := field.Type().Elem()
+			if isScalarType(elemType) {
+				break
+			}
 			if arrLen := field.Len(); arrLen > 0 {
 				for i := 0; i < arrLen; i++ {
-					if reflect.Indirect(field.Index(i)).Kind() == reflect.Struct {
-						if err := l.load(field.Index(i).Addr().Interface(), append(fieldNamePath(prefixes, &fieldStruct), fmt.Sprint(i))...); err != nil {
-							return err
-						}
-					}
+					l.loadStruct(structPtr(field.Index(i)).Interface(), st, fmt.Sprintf("%s[%d].", childNs, i), append(fieldNamePath(prefixes, &fieldStruct), fmt.Sprint(i))...)
 				}
 			} else {
-				newVal := reflect.New(field.Type().Elem()).Elem()
-				if newVal.Kind() == reflect.Struct {
-					idx := 0
-					for {
-						newVal = reflect.New(field.Type().Elem()).Elem()
-						if err := l.load(newVal.Addr().Interface(), append(fieldNamePath(prefixes, &fieldStruct), fmt.Sprint(idx))...); err != nil {
-							return err
-						} else if reflect.DeepEqual(newVal.Interface(), reflect.New(field.Type().Elem()).Elem().Interface()) {
-							break
-						} else {
-							idx++
-							field.Set(reflect.Append(field, newVal))
-						}
+				for idx := 0; ; idx++ {
+					newVal := reflect.New(elemType).Elem()
+					ptr := structPtr(newVal)
+					// probe the element with its own state, the errors only count if it exists,
+					// it exists if any field is set by a source, the defaults alone don't count
+					elemSt := st.child()
+					l.loadStruct(ptr.Interface(), elemSt, fmt.Sprintf("%s[%d].", childNs, idx), append(fieldNamePath(prefixes, &fieldStruct), fmt.Sprint(idx))...)
+					if !elemSt.configured() {
+						break
 					}
+					st.merge(elemSt)
+					field.Set(reflect.Append(field, newVal))
 				}
 			}
 		default:
 			// do nothing here, just for linter check
 		}
+		if field.Kind() == reflect.Slice || field.Kind() == reflect.Map {
+			st.requireFilled(field, f, res)
+		}
 	}
+}
 
-	return nil
+// requireFilled reports a required slice or map which is still blank after its indexed or prefixed keys are loaded
+func (st *loadState) requireFilled(field reflect.Value, f Field, res loaded) {
+	if res.source == "" && field.IsZero() && f.Tag.Get("required") == "true" && !st.failed(res.info.path) {
+		st.errs = append(st.errs, &FieldError{Path: res.info.path, Keys: res.info.keys, Err: ErrRequired})
+	}
+}
+
+// structPtr returns the struct pointer of a slice element of T or *T, a nil *T is allocated
+func structPtr(elem reflect.Value) reflect.Value {
+	if elem.Kind() == reflect.Ptr {
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+		return elem
+	}
+	return elem.Addr()
+}
+
+// loadSlice fills a slice of single values from indexed keys like HOSTS_0, HOSTS_1, until an index is in no source.
+// The field is only replaced if it is blank, or it has the default value, or in override mode.
+// It returns true if the field is set.
+func (l *Loader) loadSlice(field reflect.Value, f Field, st *loadState, ns string, isDefault bool) bool {
+	if field.Len() > 0 && !isDefault && !st.opts.override {
+		return false
+	}
+	elemType := field.Type().Elem()
+	slice := reflect.MakeSlice(field.Type(), 0, 0)
+	var fields []Field
+	var results []loaded
+	for i := 0; ; i++ {
+		key := strconv.Itoa(i)
+		elem := reflect.New(elemType).Elem()
+		ef := Field{Path: append(f.Path[:len(f.Path):len(f.Path)], key), Type: elemType, parent: &f, mapKey: key}
+		res := l.loadValue(elem, ef, st, fmt.Sprintf("%s[%d]", ns, i))
+		if res.source == "" {
+			break
+		}
+		slice = reflect.Append(slice, elem)
+		fields = append(fields, ef)
+		results = append(results, res)
+	}
+	if slice.Len() == 0 {
+		return false
+	}
+	field.Set(slice)
+	for i := range fields {
+		st.reportField(field.Index(i), fields[i], results[i])
+	}
+	return true
+}
+
+// loaded is the result of loading a field value
+type loaded struct {
+	info      *fieldInfo
+	source    string // empty if the field is not set by the loader
+	key       string
+	encrypted bool // the value is decrypted, it is redacted in reports
+}
+
+// loadValue looks up the field in default tag and sources, the later one wins.
+// It sets the field if it is blank, or if the value is from a source in override mode.
+// An empty value is treated as unset, unless empty values are allowed.
+// ns is the struct namespace of the field like "DB.Port".
+func (l *Loader) loadValue(field reflect.Value, f Field, st *loadState, ns string) loaded {
+	var value, source, key string
+	var found bool
+	// check default value first
+	defaultValue := f.Tag.Get("default")
+	if defaultValue != "" {
+		value = defaultValue
+		source = "default"
+		found = true
+	}
+	// check sources, the later one wins
+	allowEmpty := st.opts.allowEmpty || f.tag().Get("allowempty") == "true"
+	info := &fieldInfo{path: strings.Join(f.Path, ".")}
+	st.fields[ns] = info
+	var deprecated bool
+	for _, s := range l.sources {
+		if v, k, alias, ok := st.lookup(s, f, info, allowEmpty); ok {
+			value = v
+			source = s.Name()
+			key = k
+			found = true
+			deprecated = alias
+		}
+	}
+	res := loaded{info: info}
+	if deprecated {
+		slog.Warn("deprecated config key is used, rename it", "field", info.path, "source", source, "key", key)
+		if st.opts.strictAliases {
+			st.errs = append(st.errs, &FieldError{Path: info.path, Keys: info.keys, Err: fmt.Errorf("%w: %s %s", ErrDeprecatedKey, source, key)})
+		}
+	}
+	if found && isEncrypted(value) {
+		plaintext, err := st.decrypt(value)
+		if err != nil {
+			st.errs = append(st.errs, &FieldError{
+				Path: info.path,
+				Keys: info.keys,
+				Err:  fmt.Errorf("decrypt value from %s failed: %w", strings.TrimSuffix(source+" "+key, " "), err),
+			})
+			return res
+		}
+		value = plaintext
+		res.encrypted = true
+	}
+	if found {
+		// interpolation can refer to the name with or without prefix
+		st.raw[f.Key("env", true)] = value
+		st.raw[f.unprefixed().Key("env", true)] = value
+	}
+	// load value to field
+	isBlank := reflect.DeepEqual(field.Interface(), reflect.Zero(field.Type()).Interface())
+	if found && (isBlank || (st.opts.override && source != "default")) {
+		slog.Debug("Loading configuration", "field", info.path, "source", source, "key", key)
+		res.source, res.key = source, key
+		if value == "" {
+			// an allowed empty value
+			field.Set(reflect.Zero(field.Type()))
+			return res
+		}
+		if !st.noExpand && st.expands(f) {
+			expanded, err := st.expandValue(value)
+			if err != nil {
+				st.errs = append(st.errs, &FieldError{Path: info.path, Keys: info.keys, Err: err})
+				return res
+			}
+			value = expanded
+		}
+		if err := setValue(field, value, f.tag().Get("sep")); err != nil {
+			st.errs = append(st.errs, &FieldError{
+				Path: info.path,
+				Keys: info.keys,
+				Err:  fmt.Errorf("parse value from %s failed: %w", strings.TrimSuffix(source+" "+key, " "), err),
+			})
+		}
+	}
+
+	// report error if it is required but blank, slices and maps are checked after their keys like HOSTS_0 are loaded
+	kind := field.Kind()
+	if isBlank && !found && f.Tag.Get("required") == "true" && kind != reflect.Slice && kind != reflect.Map {
+		st.errs = append(st.errs, &FieldError{Path: info.path, Keys: info.keys, Err: ErrRequired})
+	}
+	return res
 }
 
-func (l *loader) getSecret(name string) string {
-	data, err := os.ReadFile(path.Join(l.Path, name))
-	if os.IsNotExist(err) {
-		return ""
-	} else if err != nil {
-		slog.Error("read secret file error", "error", err)
-		return ""
+// expands reports whether the value of the field is interpolated and resolved,
+// it is off unless WithExpand or the `expand:"true"` tag, so values like `ab${cd` are kept as they are.
+func (st *loadState) expands(f Field) bool {
+	if tag := f.Tag.Get("expand"); tag != "" {
+		return tag == "true"
+	}
+	return st.opts.expand
+}
+
+// lookup looks up the field in source s, then its deprecated aliases if it is not found, alias is true if one is used.
+// The keys consulted are added to info, the keys found are marked as used.
+func (st *loadState) lookup(s Source, f Field, info *fieldInfo, allowEmpty bool) (value, key string, alias, ok bool) {
+	v, k, ok := s.Lookup(f)
+	if k != "" {
+		info.keys = append(info.keys, s.Name()+":"+k)
+	}
+	if ok {
+		st.used[s.Name()+":"+k] = true
+	}
+	if ok && (v != "" || allowEmpty) {
+		return v, k, false, true
+	}
+	seen := map[string]bool{k: true}
+	for i := 1; i <= f.aliases(); i++ {
+		fa := f
+		fa.alias = i
+		av, ak, aok := s.Lookup(fa)
+		if ak == "" || seen[ak] {
+			continue // the tag of this source has no such alias
+		}
+		seen[ak] = true
+		info.keys = append(info.keys, s.Name()+":"+ak)
+		if aok {
+			st.used[s.Name()+":"+ak] = true
+		}
+		if aok && (av != "" || allowEmpty) {
+			return av, ak, true, true
+		}
+	}
+	return "", "", false, false
+}
+
+// reportField records where the leaf field value came from
+func (st *loadState) reportField(field reflect.Value, f Field, res loaded) {
+	fr := FieldReport{
+		Path:      res.info.path,
+		Source:    res.source,
+		Key:       res.key,
+		Value:     formatValue(field),
+		Sensitive: f.sensitive() || res.encrypted,
+	}
+	if fr.Sensitive && fr.Value != "" {
+		fr.Value = redacted
+	}
+	st.report = append(st.report, fr)
+}
+
+// configured reports whether any field is loaded from a source, rather than the default tag
+func (st *loadState) configured() bool {
+	for _, fr := range st.report {
+		if fr.Source != "" && fr.Source != "default" {
+			return true
+		}
+	}
+	return false
+}
+
+// merge the results of a child loading
+func (st *loadState) merge(child *loadState) {
+	for ns, info := range child.fields {
+		st.fields[ns] = info
+	}
+	st.errs = append(st.errs, child.errs...)
+	st.report = append(st.report, child.report...)
+	for k, v := range child.raw {
+		st.raw[k] = v
+	}
+	if st.vars == nil {
+		st.vars = child.vars
 	}
-	return strings.TrimSpace(string(data))
 }
 
 func fieldNamePath(prefixes []string, fieldStruct *reflect.StructField) []string {
 	if fieldStruct.Anonymous {
 		return prefixes
 	}
-	return append(prefixes, strcase.ToSnake(fieldStruct.Name))
+	return append(prefixes, snakeName(fieldStruct.Name))
 }
@@ -1,7 +1,9 @@
 package xconfig
 
 import (
+	"context"
 	"os"
+	"path"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,11 +21,7 @@ type testConfig struct {
 
 func TestLoadingConfig(t *testing.T) {
 	var err error
-	var l = loader{
-		Env:    true,
-		Secret: true,
-		Path:   "mock",
-	}
+	var l = New(EnvSource(), SecretSource("mock"))
 
 	// mock shell env variables
 	err = os.Setenv("APP_NAME", "env_app")
@@ -49,7 +47,7 @@ func TestLoadingConfig(t *testing.T) {
 
 	// load
 	cfg := new(testConfig)
-	err = l.load(cfg)
+	err = l.Load(cfg)
 	if err != nil {
 		t.Error(err)
 		return
@@ -61,3 +59,198 @@ func TestLoadingConfig(t *testing.T) {
 	assert.Equal(t, "secret_pwd", cfg.DB.Password)
 	assert.Equal(t, 3307, cfg.DB.Port)
 }
+
+type mapSource map[string]string
+
+func (m mapSource) Name() string {
+	return "map"
+}
+
+func (m mapSource) Lookup(field Field) (string, string, bool) {
+	key := field.Key("env", true)
+	value, ok := m[key]
+	return value, key, ok
+}
+
+func TestLoaderPrecedence(t *testing.T) {
+	t.Setenv("APP_NAME", "env_app")
+	remote := mapSource{"APP_NAME": "remote_app", "DB_NAME": "remote_name"}
+
+	// the later source wins
+	cfg := new(testConfig)
+	assert.NoError(t, New(remote, EnvSource()).Load(cfg))
+	assert.Equal(t, "env_app", cfg.AppName)
+	assert.Equal(t, "remote_name", cfg.DB.Name)
+
+	cfg = new(testConfig)
+	assert.NoError(t, New(EnvSource(), remote).Load(cfg))
+	assert.Equal(t, "remote_app", cfg.AppName)
+	assert.Equal(t, "remote_name", cfg.DB.Name)
+
+	// only default tag without sources
+	cfg = new(testConfig)
+	assert.NoError(t, New().Load(cfg))
+	assert.Equal(t, "default_app", cfg.AppName)
+}
+
+func TestLoadErrors(t *testing.T) {
+	type validateConfig struct {
+		Env   string `default:"staging" validate:"oneof=local testnet-dev testnet-prod"`
+		Token string `required:"true"`
+		DB    struct {
+			Port int    `default:"70000" env:"MYSQL_DB_PORT" validate:"max=65535"`
+			Host string `default:"localhost:5432" validate:"hostname_port"`
+		}
+	}
+	t.Setenv("MYSQL_DB_PORT", "")
+
+	err := New(EnvSource(), SecretSource("mock")).Load(new(validateConfig))
+	var le *LoadError
+	assert.ErrorAs(t, err, &le)
+	assert.ErrorIs(t, err, ErrRequired)
+	if assert.Len(t, le.Errors, 3) {
+		assert.Equal(t, "token", le.Errors[0].Path)
+		assert.Equal(t, []string{"env:TOKEN", "secret:token"}, le.Errors[0].Keys)
+		assert.Equal(t, "env", le.Errors[1].Path)
+		assert.Equal(t, "db.port", le.Errors[2].Path)
+		assert.Equal(t, []string{"env:MYSQL_DB_PORT", "secret:db_port"}, le.Errors[2].Keys)
+	}
+
+	t.Setenv("ENV", "local")
+	t.Setenv("TOKEN", "token")
+	t.Setenv("MYSQL_DB_PORT", "5432")
+	assert.NoError(t, New(EnvSource(), SecretSource("mock")).Load(new(validateConfig)))
+}
+
+func TestOptionalSection(t *testing.T) {
+	type slackConfig struct {
+		Token   string `required:"true"`
+		Channel string `default:"#alerts"`
+	}
+	type sectionConfig struct {
+		Slack   *slackConfig
+		Discord *slackConfig
+		Metrics *struct {
+			Port int `default:"9090"`
+		} `section:"always"`
+	}
+	t.Setenv("SLACK_TOKEN", "xoxb")
+
+	cfg := new(sectionConfig)
+	report, err := New(EnvSource()).Explain(cfg)
+	assert.NoError(t, err)
+	if assert.NotNil(t, cfg.Slack) {
+		assert.Equal(t, "xoxb", cfg.Slack.Token)
+		assert.Equal(t, "#alerts", cfg.Slack.Channel)
+	}
+	// the required token of the unconfigured section is not an error
+	assert.Nil(t, cfg.Discord)
+	if assert.NotNil(t, cfg.Metrics) {
+		assert.Equal(t, 9090, cfg.Metrics.Port)
+	}
+	assert.Len(t, report.Fields, 3)
+
+	// a configured section is validated
+	t.Setenv("DISCORD_CHANNEL", "#general")
+	err = New(EnvSource()).Load(new(sectionConfig))
+	assert.ErrorIs(t, err, ErrRequired)
+	assert.ErrorContains(t, err, "discord.token")
+}
+
+func TestEmbeddedPointer(t *testing.T) {
+	type Common struct {
+		Env string `default:"local"`
+	}
+	type embeddedConfig struct {
+		*Common
+		Port int `default:"8080"`
+	}
+
+	// only defaults apply, the embedded pointer is allocated anyway
+	cfg := new(embeddedConfig)
+	assert.NoError(t, New(EnvSource()).Load(cfg))
+	if assert.NotNil(t, cfg.Common) {
+		assert.Equal(t, "local", cfg.Env)
+	}
+	assert.Equal(t, 8080, cfg.Port)
+}
+
+func TestAliases(t *testing.T) {
+	type aliasConfig struct {
+		RpcUrl  string `env:"RPC_URL,LEGACY_RPC,OLD_RPC" secret:"rpc_url"`
+		Timeout int    `env:"HTTP_TIMEOUT,TIMEOUT"`
+	}
+	t.Setenv("OLD_RPC", "https://old.local")
+	t.Setenv("HTTP_TIMEOUT", "10")
+	t.Setenv("TIMEOUT", "5")
+
+	// the current name wins, then the aliases in order
+	cfg := new(aliasConfig)
+	assert.NoError(t, New(EnvSource(), SecretSource("mock")).Load(cfg))
+	assert.Equal(t, "https://old.local", cfg.RpcUrl)
+	assert.Equal(t, 10, cfg.Timeout)
+
+	t.Setenv("LEGACY_RPC", "https://legacy.local")
+	err := New(EnvSource(), SecretSource("mock")).LoadContext(context.Background(), new(aliasConfig), WithStrictAliases())
+	var le *LoadError
+	if assert.ErrorAs(t, err, &le) && assert.Len(t, le.Errors, 1) {
+		assert.ErrorIs(t, le.Errors[0], ErrDeprecatedKey)
+		assert.Equal(t, "rpc_url (env:RPC_URL, env:LEGACY_RPC, secret:rpc_url): deprecated key is used: env LEGACY_RPC", le.Errors[0].Error())
+	}
+
+	// no error if the current name is used
+	t.Setenv("RPC_URL", "https://new.local")
+	cfg = new(aliasConfig)
+	assert.NoError(t, New(EnvSource()).LoadContext(context.Background(), cfg, WithStrictAliases()))
+	assert.Equal(t, "https://new.local", cfg.RpcUrl)
+}
+
+func TestStrict(t *testing.T) {
+	type strictConfig struct {
+		Service  string
+		Password string
+		Hosts    map[string]string
+	}
+	fake := fakeSsm(t)
+	fake.Put("/prod/app/AGENT_SERVICE", "api")
+	fake.Put("/prod/app/AGENT_HOSTS_A", "a.local")
+	fake.Put("/prod/app/AGENT_PASWORD", "typo")
+	fake.Put("/prod/app/OTHER_SERVICE", "other")
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(path.Join(dir, "agent_password"), []byte("pwd"), 0o600))
+	assert.NoError(t, os.WriteFile(path.Join(dir, "agent_tokn"), []byte("typo"), 0o600))
+	t.Setenv("AGENT_SERVCE", "typo")
+	sources := []Source{AwsSsmSource("/prod/app"), EnvSource(), SecretSource(dir)}
+
+	// only warned
+	report, err := New(sources...).ExplainContext(context.Background(), new(strictConfig), WithPrefix("AGENT"), WithStrict(false))
+	assert.NoError(t, err)
+	unused := []string{"aws_ssm:AGENT_PASWORD", "env:AGENT_SERVCE", "secret:" + path.Join(dir, "agent_tokn")}
+	assert.Equal(t, unused, report.Unused)
+
+	// fails
+	cfg := new(strictConfig)
+	err = New(sources...).LoadContext(context.Background(), cfg, WithPrefix("AGENT"), WithStrict(true))
+	var ue *UnusedKeysError
+	if assert.ErrorAs(t, err, &ue) {
+		assert.Equal(t, unused, ue.Keys)
+	}
+	assert.Equal(t, "api", cfg.Service)
+	assert.Equal(t, "pwd", cfg.Password)
+	assert.Equal(t, map[string]string{"a": "a.local"}, cfg.Hosts)
+
+	// without a prefix, the nested directories like the k8s service account are not listed
+	dir = t.TempDir()
+	assert.NoError(t, os.MkdirAll(path.Join(dir, "kubernetes.io", "serviceaccount"), 0o700))
+	assert.NoError(t, os.WriteFile(path.Join(dir, "kubernetes.io", "serviceaccount", "token"), []byte("jwt"), 0o600))
+	assert.NoError(t, os.MkdirAll(path.Join(dir, "sidecar"), 0o700))
+	assert.NoError(t, os.WriteFile(path.Join(dir, "sidecar", "token"), []byte("other"), 0o600))
+	assert.NoError(t, os.WriteFile(path.Join(dir, "password"), []byte("pwd"), 0o600))
+	assert.NoError(t, os.WriteFile(path.Join(dir, "tokn"), []byte("typo"), 0o600))
+	report, err = New(SecretSource(dir)).ExplainContext(context.Background(), new(strictConfig), WithStrict(false))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"secret:" + path.Join(dir, "tokn")}, report.Unused)
+
+	// not checked by default
+	assert.NoError(t, New(sources...).LoadContext(context.Background(), new(strictConfig), WithPrefix("AGENT")))
+}
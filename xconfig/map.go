@@ -0,0 +1,117 @@
+package xconfig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// loadMap fills the entries of map[string]T field by scanning the keys with the field prefix,
+// e.g. RPC_URLS_ETHEREUM and RPC_URLS_BASE give the "ethereum" and "base" entries of RpcUrls.
+// A struct T is split by its field names, e.g. CHAINS_BASE_RPC_URL gives the "base" entry with RpcUrl.
+// The existing entries are kept, or loaded again in override mode.
+func (l *Loader) loadMap(field reflect.Value, f Field, st *loadState, ns string) {
+	mapType := field.Type()
+	if mapType.Key().Kind() != reflect.String {
+		return
+	}
+	elemType := mapType.Elem()
+	for _, key := range l.scanMapKeys(f, elemType) {
+		mapKey := reflect.ValueOf(key).Convert(mapType.Key())
+		var existing reflect.Value
+		if !field.IsNil() {
+			existing = field.MapIndex(mapKey)
+		}
+		if existing.IsValid() && !st.opts.override {
+			continue
+		}
+		elem := reflect.New(elemType).Elem()
+		if existing.IsValid() {
+			elem.Set(existing)
+		}
+		if isScalarType(elemType) {
+			ef := Field{Path: append(f.Path[:len(f.Path):len(f.Path)], key), Type: elemType, parent: &f, mapKey: key}
+			res := l.loadValue(elem, ef, st, fmt.Sprintf("%s[%s]", ns, key))
+			if res.source == "" {
+				continue // blank value
+			}
+			st.reportField(elem, ef, res)
+		} else {
+			ptr := elem
+			if elemType.Kind() == reflect.Ptr {
+				if elem.IsNil() {
+					elem.Set(reflect.New(elemType.Elem()))
+				}
+				ptr = elem
+			} else {
+				ptr = elem.Addr()
+			}
+			l.loadStruct(ptr.Interface(), st, fmt.Sprintf("%s[%s].", ns, key), append(f.Path[:len(f.Path):len(f.Path)], key)...)
+		}
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(mapType))
+		}
+		field.SetMapIndex(mapKey, elem)
+	}
+}
+
+// scanMapKeys collects the map keys from Scanner sources, in lower case and sorted
+func (l *Loader) scanMapKeys(f Field, elemType reflect.Type) []string {
+	var suffixes []string
+	if !isScalarType(elemType) {
+		suffixes = leafNames(indirectType(elemType), "")
+		// try the longest field name first
+		sort.Slice(suffixes, func(i, j int) bool { return len(suffixes[i]) > len(suffixes[j]) })
+	}
+	set := make(map[string]struct{})
+	for _, s := range l.sources {
+		scanner, ok := s.(Scanner)
+		if !ok {
+			continue
+		}
+		for _, sub := range scanner.Scan(f) {
+			sub = strings.ToLower(sub)
+			if suffixes == nil {
+				set[sub] = struct{}{}
+				continue
+			}
+			for _, suffix := range suffixes {
+				if key := strings.TrimSuffix(sub, "_"+suffix); key != sub && key != "" {
+					set[key] = struct{}{}
+					break
+				}
+			}
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// leafNames returns the snake names path of the single value fields in struct type t, joined by "_"
+func leafNames(t reflect.Type, prefix string) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		fieldStruct := t.Field(i)
+		if !fieldStruct.IsExported() {
+			continue
+		}
+		name := prefix
+		if !fieldStruct.Anonymous {
+			if name != "" {
+				name += "_"
+			}
+			name += snakeName(fieldStruct.Name)
+		}
+		if isScalarType(fieldStruct.Type) {
+			names = append(names, name)
+		} else {
+			names = append(names, leafNames(indirectType(fieldStruct.Type), name)...)
+		}
+	}
+	return names
+}
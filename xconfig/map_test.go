@@ -0,0 +1,91 @@
+package xconfig
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMap(t *testing.T) {
+	type chain struct {
+		RpcUrl  string
+		ChainID int `default:"1"`
+	}
+	type mapConfig struct {
+		RPCURLs map[string]string
+		URLs    map[string]string `required:"true"`
+		Limits  map[string]int
+		Chains  map[string]*chain
+		Preset  map[string]string
+	}
+	t.Setenv("RPC_URLS_ETHEREUM", "https://eth.local")
+	t.Setenv("RPC_URLS_BASE_SEPOLIA", "https://base-sepolia.local")
+	t.Setenv("URLS_BASE", "https://base.local")
+	t.Setenv("LIMITS_TENANT_A", "10")
+	t.Setenv("CHAINS_BASE_RPC_URL", "https://base.local")
+	t.Setenv("CHAINS_BASE_CHAIN_ID", "8453")
+	t.Setenv("CHAINS_ARBITRUM_ONE_RPC_URL", "https://arb.local")
+	t.Setenv("PRESET_KEPT", "env")
+
+	cfg := &mapConfig{Preset: map[string]string{"kept": "preset"}}
+	assert.NoError(t, New(EnvSource(), SecretSource("mock")).Load(cfg))
+	assert.Equal(t, map[string]string{
+		"ethereum":     "https://eth.local",
+		"base_sepolia": "https://base-sepolia.local",
+		"polygon":      "https://polygon.secret",
+	}, cfg.RPCURLs)
+	// a required map is satisfied by its prefixed keys
+	assert.Equal(t, map[string]string{"base": "https://base.local"}, cfg.URLs)
+	assert.Equal(t, map[string]int{"tenant_a": 10}, cfg.Limits)
+	if assert.Len(t, cfg.Chains, 2) {
+		assert.Equal(t, chain{RpcUrl: "https://base.local", ChainID: 8453}, *cfg.Chains["base"])
+		assert.Equal(t, chain{RpcUrl: "https://arb.local", ChainID: 1}, *cfg.Chains["arbitrum_one"])
+	}
+	assert.Equal(t, map[string]string{"kept": "preset"}, cfg.Preset)
+
+	os.Unsetenv("URLS_BASE")
+	err := New(EnvSource()).Load(new(mapConfig))
+	assert.ErrorIs(t, err, ErrRequired)
+	assert.ErrorContains(t, err, "urls (env:URLS): is required")
+}
+
+func TestLoadMapSsm(t *testing.T) {
+	type mapConfig struct {
+		RPCURLs map[string]string
+		Nodes   map[string]string `env:"NODE_URLS"`
+	}
+	fake := fakeSsm(t)
+	fake.Put("/prod/app/RPC_URLS_ETHEREUM", "https://eth.local")
+	fake.Put("/prod/app/RPC_URLS_BASE_SEPOLIA", "https://base-sepolia.local")
+	fake.Put("/prod/app/NODE_URLS_BASE", "https://base.local")
+
+	// plural acronyms are named like RPC_URLS in every source, a tag only names the keys of its own source
+	cfg := new(mapConfig)
+	assert.NoError(t, New(AwsSsmSource("/prod/app")).Load(cfg))
+	assert.Equal(t, map[string]string{
+		"ethereum":     "https://eth.local",
+		"base_sepolia": "https://base-sepolia.local",
+	}, cfg.RPCURLs)
+	assert.Empty(t, cfg.Nodes)
+}
+
+func TestSnakeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"RPCURLs", "rpc_urls"},
+		{"HostIPs", "host_ips"},
+		{"IDs", "ids"},
+		{"URLsByChain", "urls_by_chain"},
+		{"XYZs", "xyzs"},
+		{"RpcUrls", "rpc_urls"},
+		{"APIKeys", "api_keys"},
+		{"DBPassword", "db_password"},
+		{"RPCURL", "rpcurl"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, snakeName(tt.name), tt.name)
+	}
+}
@@ -0,0 +1,191 @@
+package xconfig
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/go-playground/validator/v10"
+)
+
+// Option configures a loading.
+type Option func(*options)
+
+type options struct {
+	timeout  time.Duration
+	attempts uint
+	delay    time.Duration
+
+	allowEmpty    bool
+	expand        bool
+	override      bool
+	prefix        string
+	validate      *validator.Validate
+	strictAliases bool
+	strict        bool
+	failUnused    bool
+	cipher        Cipher
+
+	// only for the package level functions like LoadAs, a Loader has its own sources
+	sources    []Source
+	secretPath string
+	ssmPath    string
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{attempts: 3, delay: 500 * time.Millisecond, validate: validate}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithTimeout limits the whole loading, including fetching the remote sources and resolving references.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetry retries a remote source on throttling and transient errors, up to `attempts` times,
+// with exponential backoff from `delay`. The default is 3 attempts from 500ms, 1 attempt disables retrying.
+func WithRetry(attempts uint, delay time.Duration) Option {
+	return func(o *options) {
+		o.attempts = max(attempts, 1)
+		o.delay = delay
+	}
+}
+
+// WithAllowEmpty honours empty values from sources, e.g. `FOO=` sets FOO to empty instead of leaving it unset.
+// It can also be enabled per field with the `allowempty:"true"` tag.
+func WithAllowEmpty() Option {
+	return func(o *options) {
+		o.allowEmpty = true
+	}
+}
+
+// WithExpand interpolates ${NAME} and resolves file://, ssm:// and asm:// references in all values,
+// a field can opt out with the `expand:"false"` tag. It can also be enabled per field with the `expand:"true"` tag.
+func WithExpand() Option {
+	return func(o *options) {
+		o.expand = true
+	}
+}
+
+// WithOverride lets the values from sources replace the values already in dst, e.g. reloading into a loaded config.
+// The `default` tags still only fill blank fields.
+func WithOverride() Option {
+	return func(o *options) {
+		o.override = true
+	}
+}
+
+// WithPrefix namespaces every env, secret file and ssm name with the prefix, so services sharing a pod don't collide,
+// e.g. WithPrefix("AGENT") loads DB_HOST from env AGENT_DB_HOST, secret file agent_db_host and ssm parameter AGENT_DB_HOST.
+// Names from tags are prefixed too, command line flags are not.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = strings.TrimSuffix(prefix, "_")
+	}
+}
+
+// WithValidation replaces the validator of the `validate` tags, e.g. one with custom validations, nil disables the validation.
+func WithValidation(v *validator.Validate) Option {
+	return func(o *options) {
+		o.validate = v
+	}
+}
+
+// WithStrictAliases fails the loading if a deprecated alias supplied a value, like LEGACY_RPC in `env:"RPC_URL,LEGACY_RPC"`,
+// instead of only logging a warning. Use it in CI to make sure no deployment still uses the old names.
+func WithStrictAliases() Option {
+	return func(o *options) {
+		o.strictAliases = true
+	}
+}
+
+// WithStrict warns about the keys in sources which are not used by any field, they are likely typos,
+// e.g. aws ssm parameters under the path, secret files, and env variables with the prefix of WithPrefix.
+// If fail is true, the loading fails with *UnusedKeysError.
+func WithStrict(fail bool) Option {
+	return func(o *options) {
+		o.strict = true
+		o.failUnused = fail
+	}
+}
+
+// WithCipher decrypts the values with the enc:v1: prefix, instead of the key in env CONFIG_ENCRYPTION_KEY,
+// the file of CONFIG_ENCRYPTION_KEY_FILE or the secret file config_encryption_key.
+func WithCipher(c Cipher) Option {
+	return func(o *options) {
+		o.cipher = c
+	}
+}
+
+// WithSources replaces the sources picked by env variables, for the package level functions like LoadAs.
+func WithSources(sources ...Source) Option {
+	return func(o *options) {
+		o.sources = sources
+	}
+}
+
+// WithSecretPath sets the secret directory instead of /run/secrets, for the package level functions like LoadAs.
+// It is used even if aws ssm is enabled.
+func WithSecretPath(path string) Option {
+	return func(o *options) {
+		o.secretPath = path
+	}
+}
+
+// WithSsmPath enables aws ssm parameter store with the path, instead of AWS_SSM_PARAM_STORE_PATH,
+// for the package level functions like LoadAs.
+func WithSsmPath(path string) Option {
+	return func(o *options) {
+		o.ssmPath = path
+	}
+}
+
+// fetch runs op of the named source with retries, the error is a *SourceError
+func (o *options) fetch(ctx context.Context, source, op string, fn func(ctx context.Context) error) error {
+	err := retry.Do(func() error {
+		return fn(ctx)
+	},
+		retry.Context(ctx),
+		retry.Attempts(o.attempts),
+		retry.Delay(o.delay),
+		retry.DelayType(retry.BackOffDelay),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(isRetryable),
+		retry.OnRetry(func(n uint, err error) {
+			slog.Info("retrying to fetch config source", "source", source, "n", n, "err", err)
+		}),
+	)
+	if err != nil {
+		return &SourceError{Source: source, Op: op, Err: err}
+	}
+	return nil
+}
+
+// isRetryable reports whether err is throttling or transient, like aws throttling, 5xx and network timeouts.
+// Errors of custom sources can implement `Temporary() bool` to be retried.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) && temporary.Temporary() {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return awsretry.IsErrorRetryables(awsretry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary ||
+		awsretry.IsErrorThrottles(awsretry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary
+}
@@ -0,0 +1,143 @@
+package xconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "service unavailable" }
+func (temporaryError) Temporary() bool { return true }
+
+// flakySource fails with err for the first `failures` preloads
+type flakySource struct {
+	mapSource
+	failures int
+	err      error
+	calls    int
+	block    bool
+}
+
+func (s *flakySource) Preload(ctx context.Context) error {
+	s.calls++
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if s.calls <= s.failures {
+		return s.err
+	}
+	return nil
+}
+
+func TestLoadContext(t *testing.T) {
+	type ctxConfig struct {
+		Service string
+	}
+
+	// transient errors are retried
+	s := &flakySource{mapSource: mapSource{"SERVICE": "api"}, failures: 2, err: temporaryError{}}
+	cfg := new(ctxConfig)
+	assert.NoError(t, New(s).LoadContext(context.Background(), cfg, WithRetry(3, time.Millisecond)))
+	assert.Equal(t, 3, s.calls)
+	assert.Equal(t, "api", cfg.Service)
+
+	// other errors are not
+	s = &flakySource{failures: 1, err: errors.New("access denied")}
+	err := New(s).LoadContext(context.Background(), new(ctxConfig), WithRetry(3, time.Millisecond))
+	var se *SourceError
+	assert.ErrorAs(t, err, &se)
+	assert.Equal(t, "map", se.Source)
+	assert.EqualError(t, err, "preload map source failed: access denied")
+	assert.Equal(t, 1, s.calls)
+
+	// the retries stop at the deadline
+	s = &flakySource{failures: 100, err: temporaryError{}}
+	err = New(s).LoadContext(context.Background(), new(ctxConfig), WithTimeout(50*time.Millisecond), WithRetry(100, 20*time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, s.calls, 10)
+
+	// a hanging source is cancelled
+	s = &flakySource{block: true}
+	start := time.Now()
+	err = New(s).LoadContext(context.Background(), new(ctxConfig), WithTimeout(50*time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorAs(t, err, &se)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestLoadEmptyAndOverride(t *testing.T) {
+	type emptyConfig struct {
+		Label   string `default:"def"`
+		Suffix  string `default:"def" allowempty:"true"`
+		Retries int    `default:"3"`
+		Token   string `required:"true" allowempty:"true"`
+		Hosts   map[string]string
+	}
+	t.Setenv("LABEL", "")
+	t.Setenv("SUFFIX", "")
+	t.Setenv("RETRIES", "")
+	t.Setenv("TOKEN", "")
+	prepopulated := func() *emptyConfig {
+		return &emptyConfig{Label: "pre", Suffix: "pre", Retries: 1, Token: "pre", Hosts: map[string]string{"a": "pre"}}
+	}
+	tests := []struct {
+		name string
+		cfg  *emptyConfig
+		env  map[string]string
+		opts []Option
+		want emptyConfig
+	}{
+		{
+			name: "empty is unset",
+			cfg:  new(emptyConfig),
+			want: emptyConfig{Label: "def", Retries: 3},
+		},
+		{
+			name: "allow empty",
+			cfg:  new(emptyConfig),
+			opts: []Option{WithAllowEmpty()},
+			want: emptyConfig{},
+		},
+		{
+			name: "populated is kept",
+			cfg:  prepopulated(),
+			env:  map[string]string{"LABEL": "new", "HOSTS_A": "new"},
+			want: *prepopulated(),
+		},
+		{
+			name: "override",
+			cfg:  prepopulated(),
+			env:  map[string]string{"LABEL": "new", "HOSTS_A": "new", "HOSTS_B": "new"},
+			opts: []Option{WithOverride()},
+			want: emptyConfig{Label: "new", Retries: 1, Hosts: map[string]string{"a": "new", "b": "new"}},
+		},
+		{
+			name: "override with allow empty",
+			cfg:  prepopulated(),
+			opts: []Option{WithOverride(), WithAllowEmpty()},
+			want: emptyConfig{Hosts: map[string]string{"a": "pre"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			assert.NoError(t, New(EnvSource()).LoadContext(context.Background(), tt.cfg, tt.opts...))
+			assert.Equal(t, tt.want, *tt.cfg)
+		})
+	}
+
+	// required is satisfied by an allowed empty value only
+	t.Setenv("TOKEN", "")
+	assert.NoError(t, New(EnvSource()).Load(new(emptyConfig)))
+	os.Unsetenv("TOKEN")
+	assert.ErrorIs(t, New(EnvSource()).Load(new(emptyConfig)), ErrRequired)
+}
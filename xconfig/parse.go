@@ -0,0 +1,132 @@
+package xconfig
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isScalarType reports whether t is parsed from a single value, even if it is a struct like time.Time or url.URL
+func isScalarType(t reflect.Type) bool {
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	return t == urlType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// isDelimitedSlice reports whether t is a slice of single values like []string or []time.Duration, except []byte
+func isDelimitedSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	switch indirectType(t.Elem()).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Interface:
+		return false
+	}
+	return isScalarType(t.Elem())
+}
+
+// isYAMLSequence reports whether value is a YAML list like "[a, b]" or "- a\n- b"
+func isYAMLSequence(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "[") || strings.HasPrefix(value, "- ") || strings.HasPrefix(value, "-\n")
+}
+
+// setValue parses the raw value into field.
+// It supports encoding.TextUnmarshaler (net.IP, big.Int, time.Time in RFC 3339...), time.Duration, url.URL,
+// bool, string and numbers natively, other types are parsed as YAML, like slices and maps.
+// A slice of single values is split by sep, "," if sep is empty, unless the value is a YAML list.
+func setValue(field reflect.Value, value string, sep string) error {
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := setValue(elem.Elem(), value, sep); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+	if isDelimitedSlice(field.Type()) && !isYAMLSequence(value) {
+		if sep == "" {
+			sep = ","
+		}
+		slice := reflect.MakeSlice(field.Type(), 0, strings.Count(value, sep)+1)
+		for i, item := range strings.Split(value, sep) {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := setValue(elem, item, ""); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+			slice = reflect.Append(slice, elem)
+		}
+		field.Set(slice)
+		return nil
+	}
+	switch field.Type() {
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	case urlType:
+		u, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(*u))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "", "0", "f", "false":
+			field.SetBool(false)
+		default:
+			field.SetBool(true)
+		}
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// base prefixes like 0x10 are accepted, spaces around the number are ignored
+		i, err := strconv.ParseInt(strings.TrimSpace(value), 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.TrimSpace(value), 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package xconfig
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+func TestParseTypes(t *testing.T) {
+	type parseConfig struct {
+		Timeout  time.Duration `default:"30s"`
+		Endpoint *url.URL      `default:"https://rpc.local:8545/v1"`
+		Callback url.URL       `default:"http://localhost/callback"`
+		IP       net.IP        `default:"10.0.0.1"`
+		Supply   *big.Int      `default:"1000000000000000000000"`
+		Since    time.Time     `default:"2024-01-02T03:04:05Z"`
+		Level    level         `default:"high"`
+		Ratio    float64       `default:" 0.5"`
+		Mask     uint32        `default:"0x10"`
+		Port     int           `default:"8080 "`
+		Hosts    []string      `default:"[a, b]"`
+		Optional *int
+	}
+	cfg := new(parseConfig)
+	assert.NoError(t, New().Load(cfg))
+	assert.Equal(t, 30*time.Second, cfg.Timeout)
+	assert.Equal(t, "rpc.local:8545", cfg.Endpoint.Host)
+	assert.Equal(t, "/callback", cfg.Callback.Path)
+	assert.Equal(t, "10.0.0.1", cfg.IP.String())
+	assert.Equal(t, "1000000000000000000000", cfg.Supply.String())
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), cfg.Since)
+	assert.Equal(t, level(2), cfg.Level)
+	assert.Equal(t, 0.5, cfg.Ratio)
+	assert.Equal(t, uint32(16), cfg.Mask)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, []string{"a", "b"}, cfg.Hosts)
+	assert.Nil(t, cfg.Optional)
+}
+
+func TestParseError(t *testing.T) {
+	type parseConfig struct {
+		Timeout time.Duration
+		Port    int
+	}
+	t.Setenv("TIMEOUT", "soon")
+	t.Setenv("PORT", "http")
+	err := New(EnvSource()).Load(new(parseConfig))
+	var le *LoadError
+	if assert.ErrorAs(t, err, &le) && assert.Len(t, le.Errors, 2) {
+		assert.Equal(t, "timeout", le.Errors[0].Path)
+		assert.Contains(t, le.Errors[0].Error(), `parse value from env TIMEOUT failed: time: invalid duration "soon"`)
+		assert.Equal(t, "port", le.Errors[1].Path)
+	}
+}
+
+func TestParseSlices(t *testing.T) {
+	type sliceConfig struct {
+		Hosts    []string        `default:"a.local"`
+		Ports    []int           `sep:";"`
+		Timeouts []time.Duration `default:"1s, 2s"`
+		Peers    []string
+		Tokens   []string `sensitive:"true"`
+		Levels   []level
+	}
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(path.Join(dir, "tokens_0"), []byte("t0"), 0o600))
+	assert.NoError(t, os.WriteFile(path.Join(dir, "tokens_1"), []byte("t1"), 0o600))
+	t.Setenv("HOSTS_0", "b.local")
+	t.Setenv("HOSTS_1", "c.local")
+	t.Setenv("HOSTS_3", "skipped.local")
+	t.Setenv("PORTS", "80; 443")
+	t.Setenv("PEERS", "[x, y]")
+	t.Setenv("LEVELS", "low,high")
+
+	cfg := new(sliceConfig)
+	report, err := New(EnvSource(), SecretSource(dir)).Explain(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b.local", "c.local"}, cfg.Hosts)
+	assert.Equal(t, []int{80, 443}, cfg.Ports)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, cfg.Timeouts)
+	assert.Equal(t, []string{"x", "y"}, cfg.Peers)
+	assert.Equal(t, []string{"t0", "t1"}, cfg.Tokens)
+	assert.Equal(t, []level{1, 2}, cfg.Levels)
+	assert.Contains(t, report.Fields, FieldReport{Path: "hosts.1", Source: "env", Key: "HOSTS_1", Value: "c.local"})
+	assert.Contains(t, report.Fields, FieldReport{Path: "tokens.0", Source: "secret", Key: path.Join(dir, "tokens_0"), Value: redacted, Sensitive: true})
+
+	t.Setenv("PORTS", "80;http")
+	err = New(EnvSource()).Load(new(sliceConfig))
+	assert.ErrorContains(t, err, `parse value from env PORTS failed: item 1: strconv.ParseInt: parsing "http": invalid syntax`)
+}
+
+func TestParseRequiredSlices(t *testing.T) {
+	type item struct {
+		Host string
+	}
+	type requiredConfig struct {
+		Hosts []string `required:"true"`
+		Items []item   `required:"true"`
+	}
+
+	// indexed keys satisfy required
+	t.Setenv("HOSTS_0", "a.local")
+	t.Setenv("ITEMS_0_HOST", "b.local")
+	cfg := new(requiredConfig)
+	assert.NoError(t, New(EnvSource()).Load(cfg))
+	assert.Equal(t, []string{"a.local"}, cfg.Hosts)
+	assert.Equal(t, []item{{"b.local"}}, cfg.Items)
+
+	os.Unsetenv("HOSTS_0")
+	os.Unsetenv("ITEMS_0_HOST")
+	err := New(EnvSource()).Load(new(requiredConfig))
+	var le *LoadError
+	if assert.ErrorAs(t, err, &le) && assert.Len(t, le.Errors, 2) {
+		assert.EqualError(t, le.Errors[0], "hosts (env:HOSTS): is required")
+		assert.ErrorIs(t, le.Errors[1], ErrRequired)
+	}
+}
+
+func TestParseStructSlices(t *testing.T) {
+	type item struct {
+		Host string
+		Port int `default:"80"`
+	}
+	type sliceConfig struct {
+		Items    []item
+		Pointers []*item
+		Filled   []*item
+	}
+	t.Setenv("ITEMS_0_HOST", "a")
+	t.Setenv("POINTERS_0_HOST", "b")
+	t.Setenv("POINTERS_1_HOST", "c")
+	t.Setenv("FILLED_0_PORT", "8080")
+
+	cfg := &sliceConfig{Filled: []*item{nil, {Host: "e"}}}
+	assert.NoError(t, New(EnvSource()).Load(cfg))
+	assert.Equal(t, []item{{"a", 80}}, cfg.Items)
+	assert.Equal(t, []*item{{"b", 80}, {"c", 80}}, cfg.Pointers)
+	assert.Equal(t, []*item{{"", 8080}, {"e", 80}}, cfg.Filled)
+}
@@ -0,0 +1,73 @@
+package xconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// redacted replaces the values of `sensitive:"true"` and Secret fields in reports,
+// and a non-empty Secret when it is printed, marshaled or logged
+const redacted = "[REDACTED]"
+
+// Report tells where every config field value came from.
+type Report struct {
+	Fields []FieldReport `json:"fields"`
+	// Unused are the keys in sources which are not used by any field, like "aws_ssm:DB_PASWORD", only in strict mode
+	Unused []string `json:"unused,omitempty"`
+}
+
+// FieldReport is the provenance of a config field.
+type FieldReport struct {
+	// Path is the field path like "db.port"
+	Path string `json:"path"`
+	// Source is the name of the source which provides the value, like "default" or "env",
+	// it is empty if the value is not loaded from any source.
+	Source string `json:"source"`
+	// Key is the key consulted in the source, like "DB_PORT"
+	Key string `json:"key"`
+	// Value is masked if the field is tagged `sensitive:"true"`
+	Value     string `json:"value"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+// WriteTable prints the report as a text table, e.g. at boot.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "FIELD\tSOURCE\tKEY\tVALUE")
+	for _, f := range r.Fields {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Path, f.Source, f.Key, f.Value)
+	}
+	for _, key := range r.Unused {
+		source, key, _ := strings.Cut(key, ":")
+		_, _ = fmt.Fprintf(tw, "(unused)\t%s\t%s\t\n", source, key)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON prints the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// formatValue prints the field value for reports, pointers are dereferenced
+func formatValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	// some types like url.URL implement String on the pointer
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
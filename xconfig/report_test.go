@@ -0,0 +1,40 @@
+package xconfig
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	type explainConfig struct {
+		Service string `default:"default_service"`
+		DB      struct {
+			User     string `default:"default_user"`
+			Password string `sensitive:"true"`
+			Port     int    `default:"3306" env:"MYSQL_DB_PORT"`
+		}
+		Hosts []string
+	}
+	t.Setenv("DB_USER", "env_user")
+	t.Setenv("MYSQL_DB_PORT", "3307")
+
+	report, err := New(EnvSource(), SecretSource("mock")).Explain(new(explainConfig))
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldReport{
+		{Path: "service", Source: "default", Value: "default_service"},
+		{Path: "db.user", Source: "env", Key: "DB_USER", Value: "env_user"},
+		{Path: "db.password", Source: "secret", Key: "mock/db_password", Value: "[REDACTED]", Sensitive: true},
+		{Path: "db.port", Source: "env", Key: "MYSQL_DB_PORT", Value: "3307"},
+		{Path: "hosts", Value: "[]"},
+	}, report.Fields)
+
+	var buf bytes.Buffer
+	assert.NoError(t, report.WriteTable(&buf))
+	assert.Contains(t, buf.String(), "db.password  secret   mock/db_password  [REDACTED]")
+	assert.NotContains(t, buf.String(), "secret_pwd")
+	buf.Reset()
+	assert.NoError(t, report.WriteJSON(&buf))
+	assert.NotContains(t, buf.String(), "secret_pwd")
+}
@@ -0,0 +1,42 @@
+package xconfig
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+)
+
+// Secret is a string config value which is redacted in fmt, json and slog, use Reveal to get the value.
+// It is loaded like a string and reported as sensitive, without the `sensitive:"true"` tag.
+// An empty Secret prints as empty, so a missing value is still visible.
+type Secret string
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// Reveal returns the secret value
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// String implements fmt.Stringer for %s and %v
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString implements fmt.GoStringer for %#v
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+// MarshalJSON implements json.Marshaler
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// LogValue implements slog.LogValuer
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
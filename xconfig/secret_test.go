@@ -0,0 +1,48 @@
+package xconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret(t *testing.T) {
+	type secretConfig struct {
+		User     string
+		Password Secret `validate:"required"`
+		Tokens   []Secret
+	}
+	t.Setenv("USER", "app")
+	t.Setenv("PASSWORD", "p@ssw0rd")
+	t.Setenv("TOKENS", "t1,t2")
+
+	cfg := new(secretConfig)
+	report, err := New(EnvSource()).Explain(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "p@ssw0rd", cfg.Password.Reveal())
+	assert.Equal(t, []Secret{"t1", "t2"}, cfg.Tokens)
+	assert.Contains(t, report.Fields, FieldReport{Path: "password", Source: "env", Key: "PASSWORD", Value: redacted, Sensitive: true})
+	assert.Contains(t, report.Fields, FieldReport{Path: "tokens", Source: "env", Key: "TOKENS", Value: redacted, Sensitive: true})
+
+	// redacted in fmt, json and slog
+	for _, format := range []string{"%s", "%v", "%+v", "%#v", "%q"} {
+		assert.NotContains(t, fmt.Sprintf(format, cfg), "p@ssw0rd", format)
+	}
+	assert.Contains(t, fmt.Sprintf("%+v", cfg), "Password:[REDACTED]")
+	data, err := json.Marshal(cfg)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"User":"app","Password":"[REDACTED]","Tokens":["[REDACTED]","[REDACTED]"]}`, string(data))
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("loaded", "password", cfg.Password)
+	assert.Contains(t, buf.String(), "password=[REDACTED]")
+
+	// an empty secret is visible
+	assert.Equal(t, "", Secret("").String())
+	t.Setenv("PASSWORD", "")
+	err = New(EnvSource()).Load(new(secretConfig))
+	assert.ErrorContains(t, err, "password (env:PASSWORD): failed on validation required")
+}
@@ -0,0 +1,390 @@
+package xconfig
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"syscall"
+)
+
+// Field is a config struct field being resolved, sources use it to decide which key to look up.
+type Field struct {
+	// Path is the snake_case names path from the root struct, e.g. ["db", "password"]
+	Path []string
+	// Tag is the struct tag of the field
+	Tag reflect.StructTag
+	// Type is the type of the field
+	Type reflect.Type
+
+	// for map entries, the key is the parent key and the map key joined by "_"
+	parent *Field
+	mapKey string
+	// prefix namespaces the keys, see WithPrefix
+	prefix string
+	// alias is the index of the name in the tag list, 0 is the current name, the others are deprecated
+	alias int
+}
+
+// nameTags are the tags of source keys, they can be lists with deprecated aliases like `env:"RPC_URL,LEGACY_RPC"`
+var nameTags = []string{"env", "secret", "ssm", "asm", "vault", "file"}
+
+// Key returns the custom name in struct tag `tag` if it is set,
+// otherwise the path joined by "_", upper-cased when `upper` is true.
+// For an entry of map field, it is the map field key and the map key joined by "_".
+// If the loading has a prefix, it is prepended to the key with "_".
+func (f Field) Key(tag string, upper bool) string {
+	if f.parent != nil {
+		sub := f.mapKey
+		if upper {
+			sub = strings.ToUpper(sub)
+		}
+		parent := *f.parent
+		parent.alias = f.alias
+		return parent.Key(tag, upper) + "_" + sub
+	}
+	var key string
+	if names := f.Tag.Get(tag); names != "" {
+		list := strings.Split(names, ",")
+		key = list[0]
+		if f.alias < len(list) {
+			key = list[f.alias]
+		}
+		key = strings.TrimSpace(key)
+	} else {
+		key = strings.Join(f.Path, "_")
+		if upper {
+			key = strings.ToUpper(key)
+		}
+	}
+	if f.prefix != "" {
+		if upper {
+			key = strings.ToUpper(f.prefix) + "_" + key
+		} else {
+			key = strings.ToLower(f.prefix) + "_" + key
+		}
+	}
+	return key
+}
+
+// aliases returns the max number of deprecated aliases in the name tags
+func (f Field) aliases() int {
+	var n int
+	tag := f.tag()
+	for _, name := range nameTags {
+		if names := tag.Get(name); names != "" {
+			n = max(n, strings.Count(names, ","))
+		}
+	}
+	return n
+}
+
+// unprefixed returns the field without the loading prefix
+func (f Field) unprefixed() Field {
+	if f.parent != nil {
+		parent := f.parent.unprefixed()
+		f.parent = &parent
+	}
+	f.prefix = ""
+	return f
+}
+
+// tag returns the struct tags of the field, map entries use the tags of the map field
+func (f Field) tag() reflect.StructTag {
+	if f.parent != nil {
+		return f.parent.tag()
+	}
+	return f.Tag
+}
+
+// sensitive reports whether the value is redacted in reports, by the `sensitive:"true"` tag or the Secret type
+func (f Field) sensitive() bool {
+	if f.tag().Get("sensitive") == "true" {
+		return true
+	}
+	if f.Type == nil {
+		return false
+	}
+	t := indirectType(f.Type)
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = indirectType(t.Elem())
+	}
+	return t == secretType
+}
+
+// isStruct reports whether the field is a nested config struct, rather than a single value like time.Time
+func (f Field) isStruct() bool {
+	return f.Type != nil && !isScalarType(f.Type)
+}
+
+// Lister is implemented by sources which can list all their keys, for the strict mode to find the unused ones.
+// If prefix is set by WithPrefix, only the keys with the prefix are listed.
+type Lister interface {
+	Keys(prefix string) []string
+}
+
+// Source is where config values come from, for example shell env, secret files or aws ssm.
+type Source interface {
+	// Name of the source, used in logs, e.g. "env"
+	Name() string
+	// Lookup returns the raw value of field and the key it consulted, ok is false if not found.
+	Lookup(field Field) (value string, key string, ok bool)
+}
+
+// Preloader is implemented by sources which need to fetch data before lookup, e.g. remote stores.
+type Preloader interface {
+	Preload(ctx context.Context) error
+}
+
+// Scanner is implemented by sources which can list their keys, map fields are filled by the keys with the field prefix.
+type Scanner interface {
+	// Scan returns the rest of the keys which start with the field key and "_",
+	// e.g. ["ETHEREUM", "BASE"] for RPC_URLS_ETHEREUM and RPC_URLS_BASE.
+	Scan(field Field) []string
+}
+
+// Refresher is implemented by remote sources which can tell whether their data changed since the last fetch,
+// Watch uses it to poll them periodically.
+type Refresher interface {
+	Refresh(ctx context.Context) (changed bool, err error)
+}
+
+type envSource struct{}
+
+// EnvSource looks up shell env variables, the name is the `env` tag or upper snake path like DB_PASSWORD.
+// Struct fields are skipped.
+func EnvSource() Source {
+	return envSource{}
+}
+
+func (envSource) Name() string {
+	return "env"
+}
+
+func (envSource) Lookup(field Field) (string, string, bool) {
+	if field.isStruct() {
+		return "", "", false
+	}
+	key := field.Key("env", true)
+	value, ok := os.LookupEnv(key)
+	return value, key, ok
+}
+
+func (envSource) Scan(field Field) []string {
+	var keys []string
+	for _, kv := range os.Environ() {
+		if k, _, ok := strings.Cut(kv, "="); ok {
+			keys = append(keys, k)
+		}
+	}
+	return scanKeys(keys, field.Key("env", true))
+}
+
+// Keys lists the env variables with the prefix, no env variable is listed without prefix, there are too many others.
+func (envSource) Keys(prefix string) []string {
+	if prefix == "" {
+		return nil
+	}
+	var keys []string
+	for _, kv := range os.Environ() {
+		if k, _, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, strings.ToUpper(prefix)+"_") {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+type secretSource struct {
+	roots []string
+}
+
+// SecretSource looks up files in the docker/k8s secret directories,
+// the file name is the `secret` tag or lower snake path like db_password,
+// or the path as nested directories like db/password, for secrets mounted per component.
+// With multiple roots, e.g. docker secrets and a k8s ConfigMap mount, a later root overrides the earlier ones.
+// The key of a found value is its file path, so the report shows which root it came from.
+func SecretSource(roots ...string) Source {
+	return secretSource{roots: roots}
+}
+
+func (s secretSource) Name() string {
+	return "secret"
+}
+
+func (s secretSource) Lookup(field Field) (string, string, bool) {
+	key := field.Key("secret", false)
+	names := []string{key}
+	if nested := secretPath(field); nested != key {
+		names = append(names, nested)
+	}
+	var value, file string
+	var found bool
+	for _, root := range s.roots {
+		for _, name := range names {
+			p := path.Join(root, name)
+			data, err := os.ReadFile(p)
+			if os.IsNotExist(err) || errors.Is(err, syscall.EISDIR) || errors.Is(err, syscall.ENOTDIR) {
+				continue
+			} else if err != nil {
+				slog.Error("read secret file error", "error", err)
+				continue
+			}
+			value, file, found = strings.TrimSpace(string(data)), p, true
+			break
+		}
+	}
+	if !found {
+		return "", key, false
+	}
+	return value, file, true
+}
+
+func (s secretSource) Scan(field Field) []string {
+	var keys []string
+	for _, root := range s.roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				keys = append(keys, entry.Name())
+			}
+		}
+	}
+	keys = scanKeys(keys, field.Key("secret", false))
+	// the files under the nested directory, joined by "_" like the flat names
+	for _, root := range s.roots {
+		dir := path.Join(root, secretPath(field))
+		for _, file := range listFiles(dir, 0) {
+			keys = append(keys, strings.ReplaceAll(file, "/", "_"))
+		}
+	}
+	return keys
+}
+
+// Keys lists the paths of the secret files with the prefix, the nested ones too, except the encryption key which is used by the loader.
+// Without a prefix, only the files at the top of the roots are listed, the directories may be mounted by others.
+func (s secretSource) Keys(prefix string) []string {
+	var keys []string
+	prefix = strings.ToLower(prefix)
+	for _, root := range s.roots {
+		for _, file := range listFiles(root, 0) {
+			if file == encryptionKeySecret || (prefix == "" && strings.Contains(file, "/")) {
+				continue
+			}
+			if prefix == "" || strings.HasPrefix(file, prefix+"_") || strings.HasPrefix(file, prefix+"/") {
+				keys = append(keys, path.Join(root, file))
+			}
+		}
+	}
+	return keys
+}
+
+// WatchPaths makes the secret directories and their subdirectories watchable, k8s updates the files when secrets rotate.
+func (s secretSource) WatchPaths() []string {
+	var paths []string
+	for _, root := range s.roots {
+		paths = append(paths, root)
+		for _, dir := range listDirs(root, 0) {
+			paths = append(paths, path.Join(root, dir))
+		}
+	}
+	return paths
+}
+
+// secretPath is the nested secret file path of the field like db/password, or the `secret` tag
+func secretPath(f Field) string {
+	if f.parent != nil {
+		return secretPath(*f.parent) + "/" + f.mapKey
+	}
+	if name := f.Tag.Get("secret"); name != "" {
+		return f.Key("secret", false)
+	}
+	p := strings.Join(f.Path, "/")
+	if f.prefix != "" {
+		p = strings.ToLower(f.prefix) + "/" + p
+	}
+	return p
+}
+
+// maxSecretDepth limits the nested secret directories to walk
+const maxSecretDepth = 8
+
+// kubernetesSecretDir is the k8s service account mount in the secret directory, like /run/secrets/kubernetes.io/serviceaccount
+const kubernetesSecretDir = "kubernetes.io"
+
+// listFiles returns the relative paths of files under dir, following symlinks,
+// the k8s internal entries like ..data and the service account directory are skipped.
+func listFiles(dir string, depth int) []string {
+	return walkSecretDir(dir, depth, false)
+}
+
+// listDirs returns the relative paths of subdirectories under dir, like listFiles
+func listDirs(dir string, depth int) []string {
+	return walkSecretDir(dir, depth, true)
+}
+
+func walkSecretDir(dir string, depth int, dirs bool) []string {
+	if depth > maxSecretDepth {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") || entry.Name() == kubernetesSecretDir {
+			continue
+		}
+		info, err := os.Stat(path.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			if !dirs {
+				paths = append(paths, entry.Name())
+			}
+			continue
+		}
+		if dirs {
+			paths = append(paths, entry.Name())
+		}
+		for _, sub := range walkSecretDir(path.Join(dir, entry.Name()), depth+1, dirs) {
+			paths = append(paths, entry.Name()+"/"+sub)
+		}
+	}
+	return paths
+}
+
+// scanKeys returns the rest of keys which start with prefix and "_"
+func scanKeys(keys []string, prefix string) []string {
+	var subs []string
+	prefix += "_"
+	for _, k := range keys {
+		if sub := strings.TrimPrefix(k, prefix); sub != k && sub != "" {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// mapKeys returns the keys of a values map, for scanning
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
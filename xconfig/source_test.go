@@ -0,0 +1,59 @@
+package xconfig
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretSource(t *testing.T) {
+	type secretConfig struct {
+		Token string
+		DB    struct {
+			User     string
+			Password string
+		}
+		Chains map[string]struct {
+			RpcUrl string
+		}
+	}
+	secrets, configMap := t.TempDir(), t.TempDir()
+	writeFile := func(name, data string) {
+		assert.NoError(t, os.MkdirAll(path.Dir(name), 0o700))
+		assert.NoError(t, os.WriteFile(name, []byte(data), 0o600))
+	}
+	writeFile(path.Join(secrets, "token"), "secret_token")
+	writeFile(path.Join(secrets, "db/password"), "nested_pwd")
+	writeFile(path.Join(secrets, "db/user"), "secret_user")
+	writeFile(path.Join(secrets, "chains_base_rpc_url"), "https://base.local")
+	writeFile(path.Join(configMap, "db_user"), "config_user")
+	writeFile(path.Join(configMap, "chains/ethereum/rpc_url"), "https://ethereum.local")
+
+	s := SecretSource(secrets, configMap)
+	report, err := New(s).Explain(new(secretConfig))
+	assert.NoError(t, err)
+	values := make(map[string]FieldReport)
+	for _, fr := range report.Fields {
+		values[fr.Path] = fr
+	}
+	assert.Equal(t, "secret_token", values["token"].Value)
+	assert.Equal(t, path.Join(secrets, "db/password"), values["db.password"].Key)
+	// the later root wins
+	assert.Equal(t, "config_user", values["db.user"].Value)
+	assert.Equal(t, path.Join(configMap, "db_user"), values["db.user"].Key)
+	assert.Equal(t, "https://base.local", values["chains.base.rpc_url"].Value)
+	assert.Equal(t, "https://ethereum.local", values["chains.ethereum.rpc_url"].Value)
+	assert.Equal(t, path.Join(configMap, "chains/ethereum/rpc_url"), values["chains.ethereum.rpc_url"].Key)
+
+	assert.ElementsMatch(t, []string{secrets, path.Join(secrets, "db"), configMap, path.Join(configMap, "chains"), path.Join(configMap, "chains/ethereum")},
+		s.(Watchable).WatchPaths())
+
+	// the prefix is the top directory
+	writeFile(path.Join(secrets, "agent/db/password"), "agent_pwd")
+	cfg, err := LoadAs[secretConfig](WithPrefix("AGENT"), WithSources(s))
+	assert.NoError(t, err)
+	assert.Equal(t, "agent_pwd", cfg.DB.Password)
+	assert.Empty(t, cfg.Token)
+}
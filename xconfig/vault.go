@@ -0,0 +1,364 @@
+package xconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// kubernetesTokenPath is the service account token mounted in k8s pods
+const kubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultConfig configures the HashiCorp Vault KV source.
+type VaultConfig struct {
+	// Address is like https://vault.local:8200, default is env VAULT_ADDR
+	Address string
+	// Namespace is the vault enterprise namespace, default is env VAULT_NAMESPACE
+	Namespace string
+	// Mount is the KV secrets engine mount, default "secret"
+	Mount string
+	// Path is the secret path under the mount, like "my-app/prod"
+	Path string
+	// Version is the KV secrets engine version, 1 or 2, default 2
+	Version int
+	// Auth logs in to vault, default is VaultToken with env VAULT_TOKEN
+	Auth VaultAuth
+	// HTTPClient default has a 10s timeout
+	HTTPClient *http.Client
+}
+
+// VaultAuth logs in to vault, see VaultToken, VaultAppRole and VaultKubernetes.
+// A custom auth method can log in with the client, e.g. c.Login(ctx, "auth/userpass/login/app", body).
+type VaultAuth func(ctx context.Context, c VaultClient) (*VaultLogin, error)
+
+// VaultClient calls the vault api for a VaultAuth.
+type VaultClient interface {
+	// Login posts body to an auth endpoint like "auth/approle/login", and returns the token of the auth response
+	Login(ctx context.Context, path string, body interface{}) (*VaultLogin, error)
+	// Request calls the vault api at path like "auth/token/lookup-self" with the token if it is not empty,
+	// and decodes the json response into out
+	Request(ctx context.Context, token, method, path string, body, out interface{}) error
+}
+
+// VaultLogin is the client token of a login and its lease, TTL 0 never expires.
+type VaultLogin struct {
+	Token     string
+	TTL       time.Duration
+	Renewable bool
+}
+
+// VaultToken uses a token directly, its lease is looked up to renew it before expiring.
+func VaultToken(token string) VaultAuth {
+	return func(ctx context.Context, c VaultClient) (*VaultLogin, error) {
+		var res struct {
+			Data struct {
+				TTL       int64 `json:"ttl"`
+				Renewable bool  `json:"renewable"`
+			} `json:"data"`
+		}
+		if err := c.Request(ctx, token, http.MethodGet, "auth/token/lookup-self", nil, &res); err != nil {
+			return nil, fmt.Errorf("lookup vault token failed: %w", err)
+		}
+		return &VaultLogin{Token: token, TTL: time.Duration(res.Data.TTL) * time.Second, Renewable: res.Data.Renewable}, nil
+	}
+}
+
+// VaultAppRole logs in with the AppRole auth method mounted at auth/approle.
+func VaultAppRole(roleID, secretID string) VaultAuth {
+	return func(ctx context.Context, c VaultClient) (*VaultLogin, error) {
+		return c.Login(ctx, "auth/approle/login", map[string]string{"role_id": roleID, "secret_id": secretID})
+	}
+}
+
+// VaultKubernetes logs in with the Kubernetes auth method mounted at auth/kubernetes,
+// using the pod service account token, tokenPath default is /var/run/secrets/kubernetes.io/serviceaccount/token.
+func VaultKubernetes(role, tokenPath string) VaultAuth {
+	if tokenPath == "" {
+		tokenPath = kubernetesTokenPath
+	}
+	return func(ctx context.Context, c VaultClient) (*VaultLogin, error) {
+		// the token is rotated by k8s, read it on every login
+		jwt, err := os.ReadFile(tokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("read service account token failed: %w", err)
+		}
+		return c.Login(ctx, "auth/kubernetes/login", map[string]string{"role": role, "jwt": strings.TrimSpace(string(jwt))})
+	}
+}
+
+// VaultError is an error response of vault.
+type VaultError struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *VaultError) Error() string {
+	return fmt.Sprintf("vault responded %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// Temporary makes the loader retry on rate limiting and server errors
+func (e *VaultError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+type vaultSource struct {
+	cfg VaultConfig
+
+	mu      sync.RWMutex // refresh may run in background
+	auth    *VaultLogin
+	renewAt time.Time // renew or login again after it, zero if the token never expires
+	values  map[string]string
+	version int64
+}
+
+// VaultSource looks up values in a HashiCorp Vault KV v1/v2 secret,
+// the keys are expanded like AwsSecretsManagerSource, e.g. {"db": {"password": "x"}} gives DB_PASSWORD.
+// The key is the `vault` tag or upper snake path like DB_PASSWORD.
+// The token lease is renewed on refresh, so the source can be used with Watch.
+func VaultSource(cfg VaultConfig) Source {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	if cfg.Version == 0 {
+		cfg.Version = 2
+	}
+	if cfg.Auth == nil {
+		cfg.Auth = VaultToken(os.Getenv("VAULT_TOKEN"))
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &vaultSource{cfg: cfg}
+}
+
+func (s *vaultSource) Name() string {
+	return "vault"
+}
+
+func (s *vaultSource) Lookup(field Field) (string, string, bool) {
+	key := field.Key("vault", true)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if value, ok := s.values[key]; ok {
+		return value, key, true
+	}
+	// json keys are stored upper case
+	value, ok := s.values[strings.ToUpper(key)]
+	return value, key, ok
+}
+
+func (s *vaultSource) Scan(field Field) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return scanKeys(mapKeys(s.values), strings.ToUpper(field.Key("vault", true)))
+}
+
+// Keys lists the keys of the secret
+func (s *vaultSource) Keys(prefix string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys []string
+	for k := range s.values {
+		if prefix == "" || strings.HasPrefix(k, strings.ToUpper(prefix)+"_") {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Preload logs in and reads the secret to memory
+func (s *vaultSource) Preload(ctx context.Context) error {
+	_, err := s.Refresh(ctx)
+	return err
+}
+
+// Refresh renews the token lease if it is about to expire, then reads the secret again,
+// it is changed if the KV v2 version or the KV v1 data differs.
+func (s *vaultSource) Refresh(ctx context.Context) (bool, error) {
+	token, err := s.token(ctx)
+	if err != nil {
+		return false, err
+	}
+	values, version, err := s.read(ctx, token)
+	var ve *VaultError
+	if errors.As(err, &ve) && ve.StatusCode == http.StatusForbidden {
+		// the token may be revoked, login again
+		s.mu.Lock()
+		s.auth = nil
+		s.mu.Unlock()
+		if token, err = s.token(ctx); err != nil {
+			return false, err
+		}
+		values, version, err = s.read(ctx, token)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values != nil && version == s.version && maps.Equal(values, s.values) {
+		return false, nil
+	}
+	slog.Debug("vault secret changed", "path", s.cfg.Path, "version", version)
+	s.values = values
+	s.version = version
+	return true, nil
+}
+
+// token returns a valid client token, it logs in or renews the lease when needed
+func (s *vaultSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.auth != nil && (s.renewAt.IsZero() || time.Now().Before(s.renewAt)) {
+		return s.auth.Token, nil
+	}
+	if s.auth != nil && s.auth.Renewable {
+		login, err := s.login(ctx, s.auth.Token, "auth/token/renew-self", map[string]string{})
+		if err == nil {
+			if login.Token == "" {
+				login.Token = s.auth.Token
+			}
+			s.setLogin(login)
+			return login.Token, nil
+		}
+		slog.Warn("renew vault token failed, login again", "error", err)
+	}
+	login, err := s.cfg.Auth(ctx, s)
+	if err != nil {
+		return "", fmt.Errorf("login to vault failed: %w", err)
+	}
+	s.setLogin(login)
+	return login.Token, nil
+}
+
+// setLogin keeps the login, it is renewed after 2/3 of the lease
+func (s *vaultSource) setLogin(login *VaultLogin) {
+	s.auth = login
+	s.renewAt = time.Time{}
+	if login.TTL > 0 {
+		s.renewAt = time.Now().Add(login.TTL * 2 / 3)
+	}
+}
+
+// Login implements VaultClient
+func (s *vaultSource) Login(ctx context.Context, path string, body interface{}) (*VaultLogin, error) {
+	return s.login(ctx, "", path, body)
+}
+
+// login posts to an auth endpoint and parses the auth response, token is empty for login methods
+func (s *vaultSource) login(ctx context.Context, token, path string, body interface{}) (*VaultLogin, error) {
+	var res struct {
+		Auth *struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int64  `json:"lease_duration"`
+			Renewable     bool   `json:"renewable"`
+		} `json:"auth"`
+	}
+	if err := s.Request(ctx, token, http.MethodPost, path, body, &res); err != nil {
+		return nil, err
+	}
+	if res.Auth == nil {
+		return nil, fmt.Errorf("no auth in vault response of %s", path)
+	}
+	return &VaultLogin{
+		Token:     res.Auth.ClientToken,
+		TTL:       time.Duration(res.Auth.LeaseDuration) * time.Second,
+		Renewable: res.Auth.Renewable,
+	}, nil
+}
+
+// read the secret, returns the flattened values and the KV v2 version
+func (s *vaultSource) read(ctx context.Context, token string) (map[string]string, int64, error) {
+	mount, path := strings.Trim(s.cfg.Mount, "/"), strings.Trim(s.cfg.Path, "/")
+	var data json.RawMessage
+	var version int64
+	if s.cfg.Version == 1 {
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := s.Request(ctx, token, http.MethodGet, mount+"/"+path, nil, &res); err != nil {
+			return nil, 0, fmt.Errorf("read vault secret %s/%s failed: %w", mount, path, err)
+		}
+		data = res.Data
+	} else {
+		var res struct {
+			Data struct {
+				Data     json.RawMessage `json:"data"`
+				Metadata struct {
+					Version int64 `json:"version"`
+				} `json:"metadata"`
+			} `json:"data"`
+		}
+		if err := s.Request(ctx, token, http.MethodGet, mount+"/data/"+path, nil, &res); err != nil {
+			return nil, 0, fmt.Errorf("read vault secret %s/%s failed: %w", mount, path, err)
+		}
+		data, version = res.Data.Data, res.Data.Metadata.Version
+	}
+	var obj map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // keep big numbers
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, 0, fmt.Errorf("invalid vault secret %s/%s: %w", mount, path, err)
+	}
+	values := make(map[string]string)
+	flattenJSON(values, "", obj)
+	return values, version, nil
+}
+
+// Request implements VaultClient, it calls the vault http api and decodes the json response into out
+func (s *vaultSource) Request(ctx context.Context, token, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(s.cfg.Address, "/")+"/v1/"+path, reader)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if s.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.cfg.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		ve := &VaultError{StatusCode: resp.StatusCode}
+		var res struct {
+			Errors []string `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&res) == nil {
+			ve.Errors = res.Errors
+		}
+		return ve
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,120 @@
+package xconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/crestalnetwork/crestal-go-utils/xconfig/xconfigtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultSource(t *testing.T) {
+	type dbConfig struct {
+		User     string
+		Password string
+		Port     int
+	}
+	type vaultConfig struct {
+		Token string `vault:"API_TOKEN"`
+		DB    dbConfig
+	}
+	fake := xconfigtest.NewVault()
+	fake.Mount("kv", 1)
+	fake.Put("secret/my-app", map[string]interface{}{
+		"API_TOKEN": "v2_token",
+		"db":        map[string]interface{}{"user": "v2_user", "password": "v2_pwd", "port": 5432},
+	})
+	fake.Put("kv/my-app", map[string]interface{}{"API_TOKEN": "v1_token", "DB_PASSWORD": "v1_pwd"})
+	fake.AppRole("role", "secret")
+	jwt := path.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(jwt, []byte("k8s_jwt\n"), 0o600))
+	fake.Kubernetes("my-app", "k8s_jwt")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		config VaultConfig
+		want   vaultConfig
+	}{
+		{
+			name:   "kv v2 token",
+			config: VaultConfig{Path: "my-app", Auth: VaultToken(fake.Token(time.Hour))},
+			want:   vaultConfig{Token: "v2_token", DB: dbConfig{"v2_user", "v2_pwd", 5432}},
+		},
+		{
+			name:   "kv v1 approle",
+			config: VaultConfig{Mount: "kv", Version: 1, Path: "/my-app/", Auth: VaultAppRole("role", "secret")},
+			want:   vaultConfig{Token: "v1_token", DB: dbConfig{"", "v1_pwd", 0}},
+		},
+		{
+			name:   "kv v2 kubernetes",
+			config: VaultConfig{Path: "my-app", Auth: VaultKubernetes("my-app", jwt)},
+			want:   vaultConfig{Token: "v2_token", DB: dbConfig{"v2_user", "v2_pwd", 5432}},
+		},
+		{
+			name: "kv v2 custom auth",
+			config: VaultConfig{Path: "my-app", Auth: func(ctx context.Context, c VaultClient) (*VaultLogin, error) {
+				return c.Login(ctx, "auth/approle/login", map[string]string{"role_id": "role", "secret_id": "secret"})
+			}},
+			want: vaultConfig{Token: "v2_token", DB: dbConfig{"v2_user", "v2_pwd", 5432}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Address = server.URL
+			cfg, err := LoadAs[vaultConfig](WithSources(VaultSource(tt.config)))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, cfg)
+		})
+	}
+
+	// login failures are not retried
+	_, err := LoadAs[vaultConfig](WithSources(VaultSource(VaultConfig{Address: server.URL, Path: "my-app", Auth: VaultAppRole("role", "wrong")})))
+	assert.ErrorContains(t, err, "vault responded 400: invalid role or secret ID")
+	_, err = LoadAs[vaultConfig](WithSources(VaultSource(VaultConfig{Address: server.URL, Path: "missing", Auth: VaultAppRole("role", "secret")})))
+	var ve *VaultError
+	assert.ErrorAs(t, err, &ve)
+	assert.Equal(t, http.StatusNotFound, ve.StatusCode)
+}
+
+func TestVaultSourceRefresh(t *testing.T) {
+	fake := xconfigtest.NewVault()
+	fake.Put("secret/my-app", map[string]interface{}{"DB_PASSWORD": "pwd1"})
+	fake.AppRole("role", "secret")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := context.Background()
+	s := VaultSource(VaultConfig{Address: server.URL, Path: "my-app", Auth: VaultAppRole("role", "secret")}).(*vaultSource)
+	assert.NoError(t, s.Preload(ctx))
+	assert.Equal(t, []string{"DB_PASSWORD"}, s.Keys(""))
+	changed, err := s.Refresh(ctx)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	fake.Put("secret/my-app", map[string]interface{}{"DB_PASSWORD": "pwd2"})
+	changed, err = s.Refresh(ctx)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	value, _, _ := s.Lookup(Field{Path: []string{"db", "password"}})
+	assert.Equal(t, "pwd2", value)
+
+	// the lease is renewed when it is about to expire
+	s.renewAt = time.Now()
+	_, err = s.Refresh(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.Renewals())
+	assert.Equal(t, 1, fake.Logins())
+
+	// a revoked token logs in again
+	fake.Revoke(s.auth.Token)
+	_, err = s.Refresh(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.Logins())
+}
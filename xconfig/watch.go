@@ -0,0 +1,239 @@
+package xconfig
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce waits for a burst of file events to settle, k8s swaps secrets with several renames.
+const watchDebounce = 100 * time.Millisecond
+
+// Watchable is implemented by sources backed by local files, Watch reloads the config when they change.
+type Watchable interface {
+	WatchPaths() []string
+}
+
+// ChangeFunc is called after the config is reloaded with changes,
+// `changed` is the list of changed field paths like "db.password".
+type ChangeFunc[T any] func(old, new *T, changed []string)
+
+// Snapshot is a published version of the config, don't modify it.
+type Snapshot[T any] struct {
+	Value *T
+	// Version starts from 1, increases by every published change
+	Version  uint64
+	LoadedAt time.Time
+}
+
+// Watcher keeps a config value up to date, read it by Get or Snapshot.
+type Watcher[T any] struct {
+	loader    *Loader
+	opts      *options
+	snapshot  atomic.Pointer[Snapshot[T]]
+	mu        sync.Mutex // protect callbacks and loading
+	callbacks []ChangeFunc[T]
+}
+
+// Watch loads the config with loader, then reloads it when the files of Watchable sources change.
+// If interval is greater than 0, it also polls the sources periodically,
+// Refresher sources like aws ssm only trigger a reload when their data changed.
+// The watching stops when ctx is done. The options apply to the first loading and every reload.
+func Watch[T any](ctx context.Context, l *Loader, interval time.Duration, opts ...Option) (*Watcher[T], error) {
+	w := &Watcher[T]{loader: l, opts: newOptions(opts)}
+	value := new(T)
+	if err := l.LoadContext(ctx, value, opts...); err != nil {
+		return nil, err
+	}
+	w.snapshot.Store(&Snapshot[T]{Value: value, Version: 1, LoadedAt: time.Now()})
+	go w.run(ctx, w.watchFiles(), interval)
+	return w, nil
+}
+
+// Get returns the current config, don't modify it.
+func (w *Watcher[T]) Get() *T {
+	return w.snapshot.Load().Value
+}
+
+// Snapshot returns the current config with its version.
+func (w *Watcher[T]) Snapshot() *Snapshot[T] {
+	return w.snapshot.Load()
+}
+
+// OnChange registers a callback which is called after every reload with changes.
+func (w *Watcher[T]) OnChange(fn ChangeFunc[T]) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks = append(w.callbacks, fn)
+}
+
+// Reload fetches all sources and loads the config again, publishes and notifies if anything changed.
+// If loading fails, the current config is kept.
+func (w *Watcher[T]) Reload(ctx context.Context) error {
+	return w.reload(ctx, true)
+}
+
+// reload the config, if remote is true, the remote sources are refreshed first.
+// The callbacks run without the lock, so they can call OnChange or Reload.
+func (w *Watcher[T]) reload(ctx context.Context, remote bool) error {
+	old, value, changed, err := w.update(ctx, remote)
+	if err != nil || len(changed) == 0 {
+		return err
+	}
+	w.mu.Lock()
+	callbacks := slices.Clone(w.callbacks)
+	w.mu.Unlock()
+	for _, fn := range callbacks {
+		fn(old, value, changed)
+	}
+	return nil
+}
+
+// update loads the config and publishes it if anything changed, it returns the old and new values with the changed paths
+func (w *Watcher[T]) update(ctx context.Context, remote bool) (*T, *T, []string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.timeout)
+		defer cancel()
+	}
+	if remote {
+		changed, err := w.loader.refresh(ctx, w.opts)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		// files may change without events, e.g. fsnotify is not supported
+		if !changed && len(w.loader.watchPaths()) == 0 {
+			return nil, nil, nil, nil
+		}
+	}
+	value := new(T)
+	if _, err := w.loader.load(ctx, value, w.opts); err != nil {
+		return nil, nil, nil, err
+	}
+	old := w.snapshot.Load()
+	changed := diffFields(reflect.ValueOf(old.Value).Elem(), reflect.ValueOf(value).Elem(), nil)
+	if len(changed) == 0 {
+		return nil, nil, nil, nil
+	}
+	w.snapshot.Store(&Snapshot[T]{Value: value, Version: old.Version + 1, LoadedAt: time.Now()})
+	slog.Info("config reloaded", "changed", changed, "version", old.Version+1)
+	return old.Value, value, changed, nil
+}
+
+// watchFiles starts a fsnotify watcher on the paths of Watchable sources, nil if there is nothing to watch
+func (w *Watcher[T]) watchFiles() *fsnotify.Watcher {
+	paths := w.loader.watchPaths()
+	if len(paths) == 0 {
+		return nil
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Error("create file watcher failed", "error", err)
+		return nil
+	}
+	for _, p := range paths {
+		if err := fw.Add(p); err != nil {
+			slog.Warn("watch config path failed", "path", p, "error", err)
+		}
+	}
+	return fw
+}
+
+func (w *Watcher[T]) run(ctx context.Context, fw *fsnotify.Watcher, interval time.Duration) {
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if fw != nil {
+		defer fw.Close()
+		events, errs = fw.Events, fw.Errors
+	}
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			slog.Debug("config file changed", "event", ev.String())
+			debounce.Reset(watchDebounce)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			slog.Error("watch config files error", "error", err)
+		case <-debounce.C:
+			if err := w.reload(ctx, false); err != nil {
+				slog.Error("reload config failed", "error", err)
+			}
+		case <-tick:
+			if err := w.reload(ctx, true); err != nil {
+				slog.Error("reload config failed", "error", err)
+			}
+		}
+	}
+}
+
+// watchPaths collects the existing paths of Watchable sources
+func (l *Loader) watchPaths() []string {
+	var paths []string
+	for _, s := range l.sources {
+		if ws, ok := s.(Watchable); ok {
+			for _, p := range ws.WatchPaths() {
+				if _, err := os.Stat(p); err == nil {
+					paths = append(paths, p)
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// diffFields compares two config struct values, returns the changed field paths
+func diffFields(old, new reflect.Value, prefixes []string) []string {
+	var changed []string
+	for i := 0; i < old.NumField(); i++ {
+		fieldStruct := old.Type().Field(i)
+		if !fieldStruct.IsExported() {
+			continue
+		}
+		o, n := old.Field(i), new.Field(i)
+		if o.Kind() == reflect.Ptr && !o.IsNil() && !n.IsNil() {
+			o, n = o.Elem(), n.Elem()
+		}
+		if reflect.DeepEqual(o.Interface(), n.Interface()) {
+			continue
+		}
+		names := fieldNamePath(prefixes, &fieldStruct)
+		if o.Kind() == reflect.Struct {
+			// types like time.Time have no exported fields, treat them as a whole
+			if sub := diffFields(o, n, names); len(sub) > 0 {
+				changed = append(changed, sub...)
+				continue
+			}
+		}
+		changed = append(changed, strings.Join(names, "."))
+	}
+	return changed
+}
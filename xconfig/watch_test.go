@@ -0,0 +1,145 @@
+package xconfig
+
+import (
+	"context"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	type watchConfig struct {
+		AppName string `default:"default_app"`
+		DB      struct {
+			Password string
+		}
+	}
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(path.Join(dir, "db_password"), []byte("old"), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w, err := Watch[watchConfig](ctx, New(SecretSource(dir)), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", w.Get().DB.Password)
+
+	var mu sync.Mutex
+	var changes []string
+	var oldPassword string
+	w.OnChange(func(old, new *watchConfig, changed []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		oldPassword = old.DB.Password
+		changes = changed
+	})
+
+	// rotate the secret, fsnotify triggers the reload
+	assert.NoError(t, os.WriteFile(path.Join(dir, "db_password"), []byte("new"), 0o600))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return changes != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "new", w.Get().DB.Password)
+	mu.Lock()
+	assert.Equal(t, []string{"db.password"}, changes)
+	assert.Equal(t, "old", oldPassword)
+	mu.Unlock()
+	assert.Equal(t, "default_app", w.Get().AppName)
+}
+
+// versionedSource is a fake remote source, it is changed when the version increases
+type versionedSource struct {
+	mu      sync.Mutex
+	version int
+	fetched int
+	value   string
+}
+
+func (s *versionedSource) Name() string {
+	return "versioned"
+}
+
+func (s *versionedSource) Lookup(field Field) (string, string, bool) {
+	key := field.Key("env", true)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value, key, key == "RATE_LIMIT"
+}
+
+func (s *versionedSource) Refresh(_ context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := s.version != s.fetched
+	s.fetched = s.version
+	return changed, nil
+}
+
+func (s *versionedSource) set(value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.value = value
+	s.version++
+}
+
+func TestWatchRefresh(t *testing.T) {
+	type refreshConfig struct {
+		RateLimit int
+	}
+	src := &versionedSource{value: "10"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w, err := Watch[refreshConfig](ctx, New(src), 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, w.Get().RateLimit)
+	assert.Equal(t, uint64(1), w.Snapshot().Version)
+
+	src.set("20")
+	assert.Eventually(t, func() bool {
+		return w.Get().RateLimit == 20
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(2), w.Snapshot().Version)
+
+	// unchanged version will not publish a new snapshot
+	assert.NoError(t, w.Reload(ctx))
+	assert.Equal(t, uint64(2), w.Snapshot().Version)
+}
+
+func TestWatchCallbackReload(t *testing.T) {
+	type refreshConfig struct {
+		RateLimit int
+	}
+	src := &versionedSource{value: "10"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w, err := Watch[refreshConfig](ctx, New(src), 0)
+	assert.NoError(t, err)
+
+	// callbacks can reload and register callbacks without a deadlock
+	var limits []int
+	w.OnChange(func(_, new *refreshConfig, _ []string) {
+		limits = append(limits, new.RateLimit)
+		w.OnChange(func(_, _ *refreshConfig, _ []string) {})
+		if new.RateLimit == 20 {
+			src.set("30")
+			assert.NoError(t, w.Reload(ctx))
+		}
+	})
+	src.set("20")
+	done := make(chan error)
+	go func() {
+		done <- w.Reload(ctx)
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("reload is deadlocked")
+	}
+	assert.Equal(t, []int{20, 30}, limits)
+	assert.Equal(t, uint64(3), w.Snapshot().Version)
+}
@@ -0,0 +1,144 @@
+// Package xconfigtest provides in-memory fakes of the remote config stores, to test the config loading offline.
+package xconfigtest
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// defaultPageSize is the default MaxResults of GetParametersByPath in aws
+const defaultPageSize = 10
+
+// Ssm is an in-memory fake of aws ssm parameter store, it implements xconfig.AwsSsmClient.
+// It is safe for concurrent use, parameters can be changed while a watcher is polling.
+//
+//	fake := xconfigtest.NewSsm()
+//	fake.Put("/prod/app/DB_HOST", "db.local")
+//	fake.PutSecure("/prod/app/DB_PASSWORD", "secret")
+//	xconfig.SetAwsSsmClient(fake)
+//	defer xconfig.SetAwsSsmClient(nil)
+type Ssm struct {
+	// PageSize is the page size of GetParametersByPath when MaxResults is not set, default 10 like aws
+	PageSize int32
+
+	mu     sync.RWMutex
+	params map[string]types.Parameter
+}
+
+// NewSsm returns an empty fake parameter store
+func NewSsm() *Ssm {
+	return &Ssm{params: make(map[string]types.Parameter)}
+}
+
+// Put creates or updates a String parameter, the version is increased on every update
+func (s *Ssm) Put(name, value string) {
+	s.put(name, value, types.ParameterTypeString)
+}
+
+// PutSecure creates or updates a SecureString parameter, its value is only returned with decryption
+func (s *Ssm) PutSecure(name, value string) {
+	s.put(name, value, types.ParameterTypeSecureString)
+}
+
+// Delete removes the parameter
+func (s *Ssm) Delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.params, name)
+}
+
+func (s *Ssm) put(name, value string, typ types.ParameterType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.params[name] = types.Parameter{
+		Name:             aws.String(name),
+		Value:            aws.String(value),
+		Type:             typ,
+		Version:          s.params[name].Version + 1,
+		DataType:         aws.String("text"),
+		LastModifiedDate: &now,
+	}
+}
+
+// GetParameter gets one parameter, it returns *types.ParameterNotFound if not found
+func (s *Ssm) GetParameter(_ context.Context, params *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name := aws.ToString(params.Name)
+	p, ok := s.params[name]
+	if !ok {
+		return nil, &types.ParameterNotFound{Message: aws.String("parameter " + name + " not found")}
+	}
+	p = output(p, aws.ToBool(params.WithDecryption))
+	return &ssm.GetParameterOutput{Parameter: &p}, nil
+}
+
+// GetParametersByPath gets the parameters under the path sorted by name, page by page.
+// Like aws, only the direct children are returned unless Recursive is set.
+func (s *Ssm) GetParametersByPath(_ context.Context, params *ssm.GetParametersByPathInput, _ ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	path := aws.ToString(params.Path)
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.New("the parameter path must begin with a forward slash (/)")
+	}
+	prefix := strings.TrimSuffix(path, "/") + "/"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var names []string
+	for name := range s.params {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		if !aws.ToBool(params.Recursive) && strings.Contains(rest, "/") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	start := 0
+	if params.NextToken != nil {
+		var err error
+		if start, err = strconv.Atoi(*params.NextToken); err != nil || start < 0 || start > len(names) {
+			return nil, fmt.Errorf("invalid next token %q", *params.NextToken)
+		}
+	}
+	size := int(aws.ToInt32(params.MaxResults))
+	if size <= 0 {
+		size = int(s.PageSize)
+	}
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	end := min(start+size, len(names))
+
+	res := &ssm.GetParametersByPathOutput{}
+	for _, name := range names[start:end] {
+		res.Parameters = append(res.Parameters, output(s.params[name], aws.ToBool(params.WithDecryption)))
+	}
+	if end < len(names) {
+		res.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return res, nil
+}
+
+// output copies the parameter, a SecureString value is a fake cipher text without decryption
+func output(p types.Parameter, decrypt bool) types.Parameter {
+	if p.Type == types.ParameterTypeSecureString && !decrypt {
+		p.Value = aws.String(base64.StdEncoding.EncodeToString([]byte("encrypted:" + aws.ToString(p.Value))))
+	}
+	return p
+}
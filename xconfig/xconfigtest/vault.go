@@ -0,0 +1,263 @@
+package xconfigtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vault is an in-memory fake of the HashiCorp Vault http api, with KV v1/v2 mounts,
+// token, AppRole and Kubernetes auth, it is an http.Handler to serve with httptest.
+// It is safe for concurrent use, secrets can be changed while a watcher is polling.
+//
+//	fake := xconfigtest.NewVault()
+//	fake.Put("secret/my-app", map[string]interface{}{"db": map[string]interface{}{"password": "secret"}})
+//	server := httptest.NewServer(fake)
+//	defer server.Close()
+//	src := xconfig.VaultSource(xconfig.VaultConfig{Address: server.URL, Path: "my-app", Auth: xconfig.VaultToken(fake.Token(time.Hour))})
+type Vault struct {
+	// TokenTTL is the lease of tokens issued by login, default 1h
+	TokenTTL time.Duration
+
+	mu       sync.Mutex
+	mounts   map[string]int // mount to kv version
+	secrets  map[string]*vaultSecret
+	tokens   map[string]*vaultToken
+	appRoles map[string]string // role id to secret id
+	k8sRoles map[string]string // role to service account jwt
+	logins   int
+	renewals int
+}
+
+type vaultSecret struct {
+	data    map[string]interface{}
+	version int64
+}
+
+type vaultToken struct {
+	ttl     time.Duration
+	expires time.Time // zero never expires
+}
+
+// NewVault creates a fake vault with a KV v2 engine mounted at "secret".
+func NewVault() *Vault {
+	return &Vault{
+		TokenTTL: time.Hour,
+		mounts:   map[string]int{"secret": 2},
+		secrets:  make(map[string]*vaultSecret),
+		tokens:   make(map[string]*vaultToken),
+		appRoles: make(map[string]string),
+		k8sRoles: make(map[string]string),
+	}
+}
+
+// Mount enables a KV engine of version 1 or 2 at mount
+func (v *Vault) Mount(mount string, version int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.mounts[strings.Trim(mount, "/")] = version
+}
+
+// Put writes a secret like "secret/my-app", the version increases on every put
+func (v *Vault) Put(path string, data map[string]interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	path = strings.Trim(path, "/")
+	s, ok := v.secrets[path]
+	if !ok {
+		s = &vaultSecret{}
+		v.secrets[path] = s
+	}
+	s.data = data
+	s.version++
+}
+
+// Delete removes a secret
+func (v *Vault) Delete(path string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.secrets, strings.Trim(path, "/"))
+}
+
+// Token creates a renewable token, ttl 0 never expires
+func (v *Vault) Token(ttl time.Duration) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.issue(ttl)
+}
+
+// Revoke invalidates a token
+func (v *Vault) Revoke(token string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.tokens, token)
+}
+
+// AppRole allows to login with the role id and secret id
+func (v *Vault) AppRole(roleID, secretID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.appRoles[roleID] = secretID
+}
+
+// Kubernetes allows to login as role with the service account jwt
+func (v *Vault) Kubernetes(role, jwt string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.k8sRoles[role] = jwt
+}
+
+// Logins counts the successful logins of AppRole and Kubernetes auth
+func (v *Vault) Logins() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.logins
+}
+
+// Renewals counts the token renewals
+func (v *Vault) Renewals() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.renewals
+}
+
+func (v *Vault) issue(ttl time.Duration) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	token := "hvs." + hex.EncodeToString(b)
+	t := &vaultToken{ttl: ttl}
+	if ttl > 0 {
+		t.expires = time.Now().Add(ttl)
+	}
+	v.tokens[token] = t
+	return token
+}
+
+// ServeHTTP implements the vault http api used by xconfig.VaultSource
+func (v *Vault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	path, ok := strings.CutPrefix(r.URL.Path, "/v1/")
+	if !ok {
+		vaultError(w, http.StatusNotFound)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && path == "auth/approle/login":
+		var req struct {
+			RoleID   string `json:"role_id"`
+			SecretID string `json:"secret_id"`
+		}
+		if json.NewDecoder(r.Body).Decode(&req) != nil || req.SecretID == "" || v.appRoles[req.RoleID] != req.SecretID {
+			vaultError(w, http.StatusBadRequest, "invalid role or secret ID")
+			return
+		}
+		v.loginResponse(w)
+	case r.Method == http.MethodPost && path == "auth/kubernetes/login":
+		var req struct {
+			Role string `json:"role"`
+			JWT  string `json:"jwt"`
+		}
+		if json.NewDecoder(r.Body).Decode(&req) != nil || req.JWT == "" || v.k8sRoles[req.Role] != req.JWT {
+			vaultError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		v.loginResponse(w)
+	default:
+		token, t := v.lookup(r.Header.Get("X-Vault-Token"))
+		if t == nil {
+			vaultError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		v.serveAuthed(w, r, path, token, t)
+	}
+}
+
+func (v *Vault) serveAuthed(w http.ResponseWriter, r *http.Request, path, token string, t *vaultToken) {
+	switch {
+	case r.Method == http.MethodGet && path == "auth/token/lookup-self":
+		var ttl int64
+		if !t.expires.IsZero() {
+			ttl = int64(time.Until(t.expires).Seconds())
+		}
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"ttl": ttl, "renewable": t.ttl > 0}})
+	case r.Method == http.MethodPost && path == "auth/token/renew-self":
+		if t.ttl > 0 {
+			t.expires = time.Now().Add(t.ttl)
+		}
+		v.renewals++
+		writeJSON(w, map[string]interface{}{"auth": map[string]interface{}{
+			"client_token": token, "lease_duration": int64(t.ttl.Seconds()), "renewable": t.ttl > 0,
+		}})
+	case r.Method == http.MethodGet:
+		v.serveSecret(w, path)
+	default:
+		vaultError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func (v *Vault) serveSecret(w http.ResponseWriter, path string) {
+	mount, rest, _ := strings.Cut(path, "/")
+	version, ok := v.mounts[mount]
+	if !ok {
+		vaultError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+	if version == 2 {
+		if rest, ok = strings.CutPrefix(rest, "data/"); !ok {
+			vaultError(w, http.StatusNotFound)
+			return
+		}
+	}
+	s, ok := v.secrets[mount+"/"+rest]
+	if !ok {
+		vaultError(w, http.StatusNotFound)
+		return
+	}
+	if version == 1 {
+		writeJSON(w, map[string]interface{}{"data": s.data, "lease_duration": 2764800, "renewable": false})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"data": map[string]interface{}{
+		"data":     s.data,
+		"metadata": map[string]interface{}{"version": s.version},
+	}})
+}
+
+// lookup returns the token if it is valid
+func (v *Vault) lookup(token string) (string, *vaultToken) {
+	t, ok := v.tokens[token]
+	if !ok {
+		return "", nil
+	}
+	if !t.expires.IsZero() && time.Now().After(t.expires) {
+		delete(v.tokens, token)
+		return "", nil
+	}
+	return token, t
+}
+
+func (v *Vault) loginResponse(w http.ResponseWriter) {
+	v.logins++
+	writeJSON(w, map[string]interface{}{"auth": map[string]interface{}{
+		"client_token": v.issue(v.TokenTTL), "lease_duration": int64(v.TokenTTL.Seconds()), "renewable": true,
+	}})
+}
+
+func vaultError(w http.ResponseWriter, code int, errs ...string) {
+	if errs == nil {
+		errs = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": errs})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/crestalnetwork/crestal-go-utils/xconfig"
 	"github.com/samber/oops"
 )
 
@@ -52,7 +53,7 @@ func LoadConfigFromAwsSecretsManager(cfg *Config) error {
 	cfg.Host = asm.Host
 	cfg.Port = fmt.Sprintf("%d", asm.Port)
 	cfg.Username = asm.Username
-	cfg.Password = asm.Password
+	cfg.Password = xconfig.Secret(asm.Password)
 	if cfg.Name == "" {
 		// if user set the Name field, it will override the DB Name field in SecretManager value
 		cfg.Name = asm.DBName
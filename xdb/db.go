@@ -6,6 +6,7 @@ import (
 	"log/slog"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/crestalnetwork/crestal-go-utils/xconfig"
 	sloggorm "github.com/orandin/slog-gorm"
 	"github.com/samber/oops"
 	"gorm.io/driver/postgres"
@@ -21,7 +22,7 @@ type Config struct {
 	Host               string
 	Port               string
 	Username           string
-	Password           string
+	Password           xconfig.Secret
 	Name               string
 	TranslateError     bool `default:"true"`
 	UseSlog            bool `default:"true"`
@@ -60,7 +61,7 @@ func New(config Config) (*gorm.DB, error) {
 			sloggorm.SetLogLevel(sloggorm.DefaultLogType, slog.LevelDebug),
 		)
 	}
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s connect_timeout=5", config.Host, config.Port, config.Username, config.Password, config.Name)
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s connect_timeout=5", config.Host, config.Port, config.Username, config.Password.Reveal(), config.Name)
 	var db *gorm.DB
 	var err error
 	err = retry.Do(func() error {
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/crestalnetwork/crestal-go-utils/xconfig"
 	slogmulti "github.com/samber/slog-multi"
 	slogslack "github.com/samber/slog-slack/v2"
 )
@@ -16,7 +17,7 @@ type Options struct {
 	// Debug will change the log level to debug
 	Debug bool
 	// SlackToken is the Slack bot token, if it is set and SlackChannel exists, Warn and Error level will send to it
-	SlackToken string
+	SlackToken xconfig.Secret
 	// SlackChannel is the Slack channel id, if it is set and SlackToken exists, Warn and Error level will send to it
 	SlackChannel string
 	// Release is the release version of the service, it will be added to log fields
@@ -41,7 +42,7 @@ func New(opts Options) *slog.Logger {
 				slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}),
 				slogslack.Option{
 					Level:    slog.LevelWarn,
-					BotToken: opts.SlackToken,
+					BotToken: opts.SlackToken.Reveal(),
 					Channel:  opts.SlackChannel,
 				}.NewSlackHandler(),
 			))